@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"licenseplate-plugin/internal/database"
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/services"
+	"licenseplate-plugin/internal/storage"
+	"licenseplate-plugin/migrations"
+)
+
+const usage = `usage:
+  licenseplate migrate up|down [steps]|status
+  licenseplate outbox failed [limit]|requeue <id>|discard <id>
+  licenseplate replay -channel <name> [-from <time>] [-to <time>] [-plate <plate>] [-type <type,...>] [-location <location>]`
+
+// runCommand handles CLI subcommands such as `migrate up`. It returns false
+// when args do not name a subcommand, in which case the server should start.
+func runCommand(ctx context.Context, db *database.Database, args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	switch args[0] {
+	case "migrate":
+		if err := runMigrateCommand(ctx, db, args[1:]); err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+	case "outbox":
+		svc := services.NewLicensePlateService(storage.NewPostgresStore(db))
+		if err := runOutboxCommand(ctx, svc, args[1:]); err != nil {
+			log.Fatal("Outbox command failed: ", err)
+		}
+	case "replay":
+		svc := services.NewLicensePlateService(storage.NewPostgresStore(db))
+		svc.SetEventSource(services.DefaultEventSource + "/" + getEnv("INSTANCE_ID", defaultInstanceID()))
+		if err := runReplayCommand(ctx, svc, args[1:]); err != nil {
+			log.Fatal("Replay failed: ", err)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	return true
+}
+
+func runMigrateCommand(ctx context.Context, db *database.Database, args []string) error {
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied  %03d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %03d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%03d_%-40s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate action %q (want up, down or status)", action)
+	}
+}
+
+// runOutboxCommand lets operators inspect and resolve dead-lettered outbox events.
+func runOutboxCommand(ctx context.Context, svc *services.LicensePlateService, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing outbox action\n%s", usage)
+	}
+
+	switch args[0] {
+	case "failed":
+		limit := 50
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid limit %q", args[1])
+			}
+			limit = n
+		}
+		events, err := svc.ListOutboxEvents(ctx, storage.OutboxFilter{Status: models.OutboxStatusFailed, Limit: limit})
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			fmt.Printf("%-8d %-12s attempts=%-3d created=%s error=%s\n",
+				e.ID, e.Channel, e.Attempts, e.CreatedAt.Format("2006-01-02 15:04:05"), e.LastError)
+		}
+		fmt.Printf("%d dead-lettered event(s)\n", len(events))
+		return nil
+	case "requeue", "discard":
+		if len(args) < 2 {
+			return fmt.Errorf("outbox %s needs an event id", args[0])
+		}
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid event id %q", args[1])
+		}
+		done := "requeued"
+		if args[0] == "requeue" {
+			err = svc.RequeueOutboxEvent(ctx, id)
+		} else {
+			err = svc.DiscardOutboxEvent(ctx, id)
+			done = "discarded"
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s outbox event %d\n", done, id)
+		return nil
+	default:
+		return fmt.Errorf("unknown outbox action %q (want failed, requeue or discard)", args[0])
+	}
+}
+
+// runReplayCommand re-emits historical events through the outbox. The
+// running server's publisher delivers them.
+func runReplayCommand(ctx context.Context, svc *services.LicensePlateService, args []string) error {
+	var req models.ReplayRequest
+	var from, to, types string
+
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.StringVar(&req.Channel, "channel", "", "bus channel to publish the replayed events to (required)")
+	flags.StringVar(&from, "from", "", "only events at or after this RFC 3339 time")
+	flags.StringVar(&to, "to", "", "only events before this RFC 3339 time")
+	flags.StringVar(&req.PlateNumber, "plate", "", "only events for this plate")
+	flags.StringVar(&types, "type", "", "comma-separated event types (default: all replayable)")
+	flags.StringVar(&req.Location, "location", "", "only parking events at this location")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var err error
+	if req.From, err = parseCommandTime("from", from); err != nil {
+		return err
+	}
+	if req.To, err = parseCommandTime("to", to); err != nil {
+		return err
+	}
+	if types != "" {
+		req.EventTypes = strings.Split(types, ",")
+	}
+
+	result, err := svc.ReplayEvents(ctx, req)
+	if result != nil {
+		for eventType, n := range result.Enqueued {
+			fmt.Printf("%-26s %d\n", eventType, n)
+		}
+		fmt.Printf("enqueued %d event(s) to %s (correlation id %s)\n", result.Total, result.Channel, result.CorrelationID)
+	}
+	return err
+}
+
+// parseCommandTime parses an optional RFC 3339 flag value.
+func parseCommandTime(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -%s %q, use RFC 3339", name, value)
+	}
+	return t, nil
+}
+
+// applyMigrations brings the schema up to date on startup.
+func applyMigrations(ctx context.Context, db *database.Database) error {
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		log.Printf("Applied %d database migration(s)", len(applied))
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Listen subscribes to a Postgres NOTIFY channel on a dedicated connection
+// and signals on the returned channel whenever a notification arrives.
+// Bursts are coalesced into a single pending signal. The listener
+// reconnects on its own and signals after reconnecting, since notifications
+// sent while disconnected are lost. It stops when ctx is cancelled.
+func (db *Database) Listen(ctx context.Context, channel string) (<-chan struct{}, error) {
+	listener := pq.NewListener(db.connectionString, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected:
+			log.Printf("[Database] LISTEN %s disconnected: %v", channel, err)
+		case pq.ListenerEventReconnected:
+			log.Printf("[Database] LISTEN %s reconnected", channel)
+		case pq.ListenerEventConnectionAttemptFailed:
+			log.Printf("[Database] LISTEN %s reconnect failed: %v", channel, err)
+		}
+	})
+
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	wake := make(chan struct{}, 1)
+	signal := func() {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+
+	go func() {
+		defer listener.Close()
+
+		// Ping now and then so a silently dropped connection is noticed
+		ping := time.NewTicker(90 * time.Second)
+		defer ping.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-listener.Notify:
+				// A nil notification means the connection was re-established
+				signal()
+			case <-ping.C:
+				go listener.Ping()
+			}
+		}
+	}()
+
+	return wake, nil
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"licenseplate-plugin/migrations"
+)
+
+func TestNewMigratorParsesFiles(t *testing.T) {
+	files := fstest.MapFS{
+		"010_add_index.up.sql":       {Data: []byte("CREATE INDEX i ON t (c);")},
+		"002_create_table.up.sql":    {Data: []byte("CREATE TABLE t (c INT);")},
+		"002_create_table.down.sql":  {Data: []byte("DROP TABLE t;")},
+		"003_no_down.up.sql":         {Data: []byte("ALTER TABLE t ADD d INT;")},
+		"README.md":                  {Data: []byte("not a migration")},
+		"004_wrong_suffix.sql":       {Data: []byte("SELECT 1;")},
+		"legacy/005_nested.up.sql":   {Data: []byte("SELECT 1;")},
+		"006_direction.sideways.sql": {Data: []byte("SELECT 1;")},
+	}
+
+	m, err := NewMigrator(nil, files)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+
+	want := []Migration{
+		{Version: 2, Name: "create_table", Up: "CREATE TABLE t (c INT);", Down: "DROP TABLE t;"},
+		{Version: 3, Name: "no_down", Up: "ALTER TABLE t ADD d INT;"},
+		{Version: 10, Name: "add_index", Up: "CREATE INDEX i ON t (c);"},
+	}
+	if len(m.migrations) != len(want) {
+		t.Fatalf("migrations = %+v, want %+v", m.migrations, want)
+	}
+	for i := range want {
+		if m.migrations[i] != want[i] {
+			t.Errorf("migration %d = %+v, want %+v", i, m.migrations[i], want[i])
+		}
+	}
+}
+
+func TestNewMigratorRejectsInvalidSets(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{
+			name: "conflicting names for one version",
+			files: fstest.MapFS{
+				"001_create_plates.up.sql": {Data: []byte("SELECT 1;")},
+				"001_create_events.up.sql": {Data: []byte("SELECT 2;")},
+			},
+			wantErr: "conflicting names",
+		},
+		{
+			name: "same version written two ways",
+			files: fstest.MapFS{
+				"001_create_plates.up.sql": {Data: []byte("SELECT 1;")},
+				"1_create_plates.up.sql":   {Data: []byte("SELECT 2;")},
+			},
+			wantErr: "more than one up script",
+		},
+		{
+			name: "missing up script",
+			files: fstest.MapFS{
+				"001_create_plates.up.sql": {Data: []byte("SELECT 1;")},
+				"002_add_column.down.sql":  {Data: []byte("SELECT 2;")},
+			},
+			wantErr: "002_add_column has no up script",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMigrator(nil, tt.files)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("NewMigrator = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestEmbeddedMigrations checks that the shipped migrations load and are
+// numbered without gaps.
+func TestEmbeddedMigrations(t *testing.T) {
+	m, err := NewMigrator(nil, migrations.FS)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	if len(m.migrations) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+	for i, mig := range m.migrations {
+		if mig.Version != i+1 {
+			t.Errorf("migration %d_%s at position %d, want version %d", mig.Version, mig.Name, i, i+1)
+		}
+		if mig.Down == "" {
+			t.Errorf("migration %d_%s has no down script", mig.Version, mig.Name)
+		}
+	}
+}
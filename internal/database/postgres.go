@@ -1,58 +1,122 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"log"
+	"net/url"
+	"strings"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
-type Database struct {
-	connectionString string
+// Options configures the connection pool shared by every query.
+// Zero values fall back to the defaults from DefaultOptions.
+type Options struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
 }
 
-func NewDatabase(connectionString string) *Database {
-	return &Database{
-		connectionString: connectionString,
+// DefaultOptions returns pool settings suitable for a single plugin replica.
+func DefaultOptions() Options {
+	return Options{
+		MaxOpenConns:    25,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
 	}
 }
 
-func (db *Database) GetConnection() (*sql.DB, error) {
-	conn, err := sql.Open("postgres", db.connectionString)
+// Database wraps one long-lived *sql.DB pool. It is safe for concurrent use
+// and should be created once at startup and closed on shutdown.
+type Database struct {
+	connectionString string
+	pool             *sql.DB
+}
+
+// NewDatabase opens the pool. Sessions use the UTC time zone unless the
+// connection string sets one, so NOW() agrees with the UTC times the
+// stores write to TIMESTAMP columns.
+func NewDatabase(connectionString string, opts Options) (*Database, error) {
+	connectionString = withSessionTimeZone(connectionString, "UTC")
+	pool, err := sql.Open("postgres", connectionString)
 	if err != nil {
-		log.Println("[Database] Cannot connect to database:", err)
+		log.Println("[Database] Cannot open database:", err)
 		return nil, err
 	}
 
-	if err := conn.Ping(); err != nil {
-		log.Println("[Database] Cannot ping database:", err)
-		return nil, err
+	defaults := DefaultOptions()
+	if opts.MaxOpenConns <= 0 {
+		opts.MaxOpenConns = defaults.MaxOpenConns
+	}
+	if opts.MaxIdleConns <= 0 {
+		opts.MaxIdleConns = defaults.MaxIdleConns
 	}
+	if opts.ConnMaxLifetime <= 0 {
+		opts.ConnMaxLifetime = defaults.ConnMaxLifetime
+	}
+	if opts.ConnMaxIdleTime <= 0 {
+		opts.ConnMaxIdleTime = defaults.ConnMaxIdleTime
+	}
+
+	pool.SetMaxOpenConns(opts.MaxOpenConns)
+	pool.SetMaxIdleConns(opts.MaxIdleConns)
+	pool.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	pool.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
 
-	return conn, nil
+	return &Database{
+		connectionString: connectionString,
+		pool:             pool,
+	}, nil
 }
 
-func (db *Database) QueryRow(query string, args ...any) *sql.Row {
-	conn, err := db.GetConnection()
-	if err != nil {
-		return nil
+// withSessionTimeZone adds a timezone run-time parameter to a URL or
+// key=value connection string that does not set one.
+func withSessionTimeZone(connectionString, zone string) string {
+	if strings.HasPrefix(connectionString, "postgres://") || strings.HasPrefix(connectionString, "postgresql://") {
+		u, err := url.Parse(connectionString)
+		if err != nil {
+			// Left for sql.Open to report
+			return connectionString
+		}
+		q := u.Query()
+		if q.Get("timezone") == "" {
+			q.Set("timezone", zone)
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
 	}
-	defer conn.Close()
-
-	return conn.QueryRow(query, args...)
+	if strings.Contains(connectionString, "timezone=") {
+		return connectionString
+	}
+	return strings.TrimSpace(connectionString + " timezone=" + zone)
 }
 
-func (db *Database) Query(query string, args ...any) (*sql.Rows, error) {
-	conn, err := db.GetConnection()
-	if err != nil {
-		return nil, err
+// Ping verifies that the database is reachable.
+func (db *Database) Ping(ctx context.Context) error {
+	if err := db.pool.PingContext(ctx); err != nil {
+		log.Println("[Database] Cannot ping database:", err)
+		return err
 	}
-	// Note: Caller must close rows AND call conn.Close() after use
-	// We'll handle this in the service layer
+	return nil
+}
+
+// Close closes the underlying pool. It must only be called on shutdown.
+func (db *Database) Close() error {
+	return db.pool.Close()
+}
+
+func (db *Database) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.pool.QueryRowContext(ctx, query, args...)
+}
 
-	rows, err := conn.Query(query, args...)
+// Query runs a query on the shared pool. The caller must close the returned rows.
+func (db *Database) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := db.pool.QueryContext(ctx, query, args...)
 	if err != nil {
-		conn.Close()
 		log.Println("[Database] Cannot execute query:", err)
 		return nil, err
 	}
@@ -60,14 +124,8 @@ func (db *Database) Query(query string, args ...any) (*sql.Rows, error) {
 	return rows, nil
 }
 
-func (db *Database) Execute(query string, args ...any) (int64, error) {
-	conn, err := db.GetConnection()
-	if err != nil {
-		return 0, err
-	}
-	defer conn.Close()
-
-	result, err := conn.Exec(query, args...)
+func (db *Database) Execute(ctx context.Context, query string, args ...any) (int64, error) {
+	result, err := db.pool.ExecContext(ctx, query, args...)
 	if err != nil {
 		log.Println("[Database] Cannot execute query:", err)
 		return 0, err
@@ -81,3 +139,28 @@ func (db *Database) Execute(query string, args ...any) (int64, error) {
 
 	return rowsAffected, nil
 }
+
+// BeginTx starts a transaction on the shared pool.
+func (db *Database) BeginTx(ctx context.Context) (*Tx, error) {
+	tx, err := db.pool.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println("[Database] Cannot begin transaction:", err)
+		return nil, err
+	}
+	return &Tx{tx: tx}, nil
+}
+
+// WithinTx runs fn inside a transaction, committing if it returns nil and
+// rolling back otherwise.
+func (db *Database) WithinTx(ctx context.Context, fn func(tx *Tx) error) error {
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.tx.Commit()
+}
@@ -0,0 +1,20 @@
+package database
+
+import "testing"
+
+func TestWithSessionTimeZone(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"postgres://u:p@db:5432/plates?sslmode=disable", "postgres://u:p@db:5432/plates?sslmode=disable&timezone=UTC"},
+		{"postgresql://db/plates", "postgresql://db/plates?timezone=UTC"},
+		{"postgres://db/plates?timezone=Europe%2FBerlin", "postgres://db/plates?timezone=Europe%2FBerlin"},
+		{"host=db dbname=plates sslmode=disable", "host=db dbname=plates sslmode=disable timezone=UTC"},
+		{"host=db timezone=Europe/Berlin", "host=db timezone=Europe/Berlin"},
+	}
+	for _, tt := range tests {
+		if got := withSessionTimeZone(tt.in, "UTC"); got != tt.want {
+			t.Errorf("withSessionTimeZone(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log"
+)
+
+// Executor is implemented by both *Database and *Tx so queries can be written
+// once and run either on the pool or inside a transaction.
+type Executor interface {
+	QueryRow(ctx context.Context, query string, args ...any) *sql.Row
+	Query(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	Execute(ctx context.Context, query string, args ...any) (int64, error)
+}
+
+// Tx is a database transaction with the same query helpers as Database.
+type Tx struct {
+	tx *sql.Tx
+}
+
+func (t *Tx) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return t.tx.QueryRowContext(ctx, query, args...)
+}
+
+// Query runs a query inside the transaction. The caller must close the returned rows.
+func (t *Tx) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := t.tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Println("[Database] Cannot execute query:", err)
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func (t *Tx) Execute(ctx context.Context, query string, args ...any) (int64, error) {
+	result, err := t.tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Println("[Database] Cannot execute query:", err)
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Println("[Database] Cannot retrieve affected rows:", err)
+		return 0, err
+	}
+
+	return rowsAffected, nil
+}
@@ -0,0 +1,44 @@
+// Package eventbus carries events between the plugin and the rest of the hub.
+// Callers depend on the EventBus interface; Redis pub/sub, Redis Streams and
+// in-memory backends implement it.
+package eventbus
+
+import (
+    "context"
+)
+
+// Handler processes one message received on channel. Backends with
+// acknowledgements (Redis Streams) redeliver messages whose handler returned
+// an error; the others log the error and move on.
+type Handler func(ctx context.Context, channel, message string) error
+
+// Publisher sends messages to a channel.
+type Publisher interface {
+    Publish(ctx context.Context, channel, message string) error
+}
+
+// Subscriber delivers messages from a channel to a handler.
+type Subscriber interface {
+    // Subscribe starts delivering messages on channel to handler in the
+    // background until ctx is cancelled. It returns once the subscription
+    // is established.
+    Subscribe(ctx context.Context, channel string, handler Handler) error
+}
+
+// EventBus publishes and subscribes to messages on named channels.
+type EventBus interface {
+    Publisher
+    Subscriber
+    // Connected reports whether all subscriptions are currently live.
+    Connected() bool
+    // Close releases the backend's resources. Active subscriptions end.
+    Close() error
+}
+
+// PublishFunc adapts an ordinary function to the Publisher interface.
+type PublishFunc func(ctx context.Context, channel, message string) error
+
+// Publish calls f(ctx, channel, message).
+func (f PublishFunc) Publish(ctx context.Context, channel, message string) error {
+    return f(ctx, channel, message)
+}
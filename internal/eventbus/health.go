@@ -0,0 +1,108 @@
+package eventbus
+
+import (
+    "context"
+    "log"
+    "sync"
+    "time"
+)
+
+const (
+    minReconnectDelay = time.Second
+    maxReconnectDelay = 30 * time.Second
+)
+
+// linkHealth tracks whether each subscription of a bus is currently
+// connected and logs when one is lost or recovers.
+type linkHealth struct {
+    mu    sync.Mutex
+    links map[*link]struct{}
+}
+
+// link is the connection state of one subscription, guarded by linkHealth.mu.
+type link struct {
+    health      *linkHealth
+    channel     string
+    connected   bool
+    established bool      // Connected at least once
+    downSince   time.Time // When the link was lost, if !connected
+}
+
+// register adds a disconnected link for a new subscription to channel.
+func (h *linkHealth) register(channel string) *link {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    if h.links == nil {
+        h.links = make(map[*link]struct{})
+    }
+    l := &link{health: h, channel: channel, downSince: time.Now()}
+    h.links[l] = struct{}{}
+    return l
+}
+
+// Connected reports whether every active subscription is connected.
+func (h *linkHealth) Connected() bool {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    for l := range h.links {
+        if !l.connected {
+            return false
+        }
+    }
+    return true
+}
+
+// close forgets the link once its subscription has ended.
+func (l *link) close() {
+    l.health.mu.Lock()
+    defer l.health.mu.Unlock()
+    delete(l.health.links, l)
+}
+
+// up marks the link as connected.
+func (l *link) up() {
+    l.health.mu.Lock()
+    defer l.health.mu.Unlock()
+    if l.connected {
+        return
+    }
+    if l.established {
+        log.Printf("[eventbus] %s recovered after %s", l.channel, time.Since(l.downSince).Round(time.Second))
+    } else {
+        log.Printf("[eventbus] subscribed to %s", l.channel)
+    }
+    l.connected = true
+    l.established = true
+}
+
+// down marks the link as disconnected because of err; the caller retries
+// after retryIn.
+func (l *link) down(err error, retryIn time.Duration) {
+    l.health.mu.Lock()
+    defer l.health.mu.Unlock()
+    if l.connected {
+        l.connected = false
+        l.downSince = time.Now()
+        log.Printf("[eventbus] lost %s, resubscribing in %s: %v", l.channel, retryIn, err)
+        return
+    }
+    log.Printf("[eventbus] subscribing to %s failed, retrying in %s: %v", l.channel, retryIn, err)
+}
+
+// nextDelay doubles delay up to maxReconnectDelay.
+func nextDelay(delay time.Duration) time.Duration {
+    return min(delay*2, maxReconnectDelay)
+}
+
+// sleepCtx waits for d or until ctx is done, reporting whether the full
+// delay elapsed.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+    t := time.NewTimer(d)
+    defer t.Stop()
+    select {
+    case <-ctx.Done():
+        return false
+    case <-t.C:
+        return true
+    }
+}
@@ -0,0 +1,126 @@
+package eventbus
+
+import (
+    "context"
+    "errors"
+    "log"
+    "sync"
+)
+
+// ErrClosed is returned when publishing to a closed bus.
+var ErrClosed = errors.New("eventbus: closed")
+
+// memoryBufferSize is the number of undelivered messages a subscriber may
+// queue before Publish blocks.
+const memoryBufferSize = 256
+
+// MemoryBus is an in-process EventBus built on Go channels. Every
+// subscriber of a channel receives every message published to it, in
+// publish order. It lets the plugin and its tests run without Redis.
+type MemoryBus struct {
+    mu     sync.RWMutex
+    subs   map[string][]*memorySubscription
+    closed bool
+}
+
+type memorySubscription struct {
+    messages chan string
+    done     chan struct{}   // Closed by Close
+    stop     <-chan struct{} // The subscriber's ctx.Done()
+}
+
+// NewMemoryBus creates an empty in-memory bus.
+func NewMemoryBus() *MemoryBus {
+    return &MemoryBus{subs: make(map[string][]*memorySubscription)}
+}
+
+// Publish queues message for every current subscriber of channel. It
+// blocks while a subscriber's buffer is full, until ctx is done.
+func (b *MemoryBus) Publish(ctx context.Context, channel, message string) error {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    if b.closed {
+        return ErrClosed
+    }
+
+    for _, s := range b.subs[channel] {
+        select {
+        case s.messages <- message:
+        case <-s.done:
+        case <-s.stop:
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+    return nil
+}
+
+// Subscribe delivers messages on channel to handler until ctx is cancelled
+// or the bus is closed.
+func (b *MemoryBus) Subscribe(ctx context.Context, channel string, handler Handler) error {
+    s := &memorySubscription{
+        messages: make(chan string, memoryBufferSize),
+        done:     make(chan struct{}),
+        stop:     ctx.Done(),
+    }
+
+    b.mu.Lock()
+    if b.closed {
+        b.mu.Unlock()
+        return ErrClosed
+    }
+    b.subs[channel] = append(b.subs[channel], s)
+    b.mu.Unlock()
+
+    go func() {
+        defer b.unsubscribe(channel, s)
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-s.done:
+                return
+            case message := <-s.messages:
+                if err := handler(ctx, channel, message); err != nil {
+                    log.Printf("[eventbus] handler failed for message on %s: %v", channel, err)
+                }
+            }
+        }
+    }()
+    return nil
+}
+
+func (b *MemoryBus) unsubscribe(channel string, s *memorySubscription) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    subs := b.subs[channel]
+    for i, other := range subs {
+        if other == s {
+            b.subs[channel] = append(subs[:i:i], subs[i+1:]...)
+            break
+        }
+    }
+}
+
+// Connected reports true until the bus is closed.
+func (b *MemoryBus) Connected() bool {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    return !b.closed
+}
+
+// Close ends all subscriptions. Messages still queued are dropped.
+func (b *MemoryBus) Close() error {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.closed {
+        return nil
+    }
+    b.closed = true
+    for _, subs := range b.subs {
+        for _, s := range subs {
+            close(s.done)
+        }
+    }
+    return nil
+}
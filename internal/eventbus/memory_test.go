@@ -0,0 +1,78 @@
+package eventbus
+
+import (
+    "context"
+    "fmt"
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestMemoryBusDeliversToEverySubscriberInOrder(t *testing.T) {
+    ctx := context.Background()
+    bus := NewMemoryBus()
+    defer bus.Close()
+
+    const n = 100
+    var wg sync.WaitGroup
+    received := make([][]string, 2)
+    for i := range received {
+        i := i
+        wg.Add(n)
+        err := bus.Subscribe(ctx, "events", func(ctx context.Context, channel, message string) error {
+            received[i] = append(received[i], message)
+            wg.Done()
+            return nil
+        })
+        if err != nil {
+            t.Fatalf("subscribe: %v", err)
+        }
+    }
+
+    for i := 0; i < n; i++ {
+        if err := bus.Publish(ctx, "events", fmt.Sprintf("event-%d", i)); err != nil {
+            t.Fatalf("publish: %v", err)
+        }
+    }
+    // Other channels are not delivered to these subscribers
+    if err := bus.Publish(ctx, "other", "ignored"); err != nil {
+        t.Fatalf("publish: %v", err)
+    }
+    wg.Wait()
+
+    for i, messages := range received {
+        if len(messages) != n {
+            t.Fatalf("subscriber %d received %d messages, want %d", i, len(messages), n)
+        }
+        for j, message := range messages {
+            if want := fmt.Sprintf("event-%d", j); message != want {
+                t.Errorf("subscriber %d message %d = %q, want %q", i, j, message, want)
+            }
+        }
+    }
+}
+
+func TestMemoryBusCancelledSubscriberDoesNotBlockPublish(t *testing.T) {
+    bus := NewMemoryBus()
+    defer bus.Close()
+
+    subCtx, cancel := context.WithCancel(context.Background())
+    block := make(chan struct{})
+    err := bus.Subscribe(subCtx, "events", func(ctx context.Context, channel, message string) error {
+        <-block
+        return nil
+    })
+    if err != nil {
+        t.Fatalf("subscribe: %v", err)
+    }
+    cancel()
+    close(block)
+
+    ctx, cancelPublish := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancelPublish()
+    for i := 0; i < 2*memoryBufferSize; i++ {
+        if err := bus.Publish(ctx, "events", "message"); err != nil {
+            t.Fatalf("publish %d: %v", i, err)
+        }
+    }
+}
@@ -0,0 +1,119 @@
+package eventbus
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "net"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+const (
+    // pubsubPingInterval is how long a subscription may stay silent before
+    // it is pinged to detect a dead connection.
+    pubsubPingInterval = 30 * time.Second
+    // pubsubSubscribeTimeout bounds the wait for the SUBSCRIBE confirmation.
+    pubsubSubscribeTimeout = 5 * time.Second
+)
+
+// RedisPubSub is an EventBus backed by Redis PUB/SUB. Delivery is
+// fire-and-forget: messages published while no subscriber is connected
+// are lost.
+type RedisPubSub struct {
+    client *redis.Client
+    health linkHealth
+}
+
+// NewRedisPubSub creates a pub/sub bus on client. The bus owns the client
+// and closes it in Close.
+func NewRedisPubSub(client *redis.Client) *RedisPubSub {
+    return &RedisPubSub{client: client}
+}
+
+// Publish publishes a string message to the given channel.
+func (b *RedisPubSub) Publish(ctx context.Context, channel, message string) error {
+    return b.client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe calls handler for each message on channel, one at a time, in
+// the order received. The subscription is supervised: if Redis is
+// unreachable or the connection drops, it resubscribes with exponential
+// backoff until ctx is cancelled. Messages published while disconnected
+// are lost.
+func (b *RedisPubSub) Subscribe(ctx context.Context, channel string, handler Handler) error {
+    l := b.health.register(channel)
+
+    go func() {
+        defer l.close()
+
+        delay := minReconnectDelay
+        for {
+            connected, err := b.receive(ctx, channel, handler, l)
+            if ctx.Err() != nil {
+                log.Printf("[eventbus] context canceled, unsubscribed from %s", channel)
+                return
+            }
+            if connected {
+                delay = minReconnectDelay
+            }
+            l.down(err, delay)
+            if !sleepCtx(ctx, delay) {
+                return
+            }
+            delay = nextDelay(delay)
+        }
+    }()
+    return nil
+}
+
+// receive runs one subscription session until the connection fails or ctx
+// is cancelled. It reports whether the subscription was established.
+func (b *RedisPubSub) receive(ctx context.Context, channel string, handler Handler, l *link) (bool, error) {
+    sub := b.client.Subscribe(ctx, channel)
+    defer sub.Close()
+    // go-redis does not watch ctx while blocked reading; closing unblocks it
+    stop := context.AfterFunc(ctx, func() { _ = sub.Close() })
+    defer stop()
+
+    reply, err := sub.ReceiveTimeout(ctx, pubsubSubscribeTimeout)
+    if err != nil {
+        return false, err
+    }
+    if _, ok := reply.(*redis.Subscription); !ok {
+        return false, fmt.Errorf("unexpected reply to SUBSCRIBE: %T", reply)
+    }
+    l.up()
+
+    for {
+        msg, err := sub.ReceiveTimeout(ctx, pubsubPingInterval)
+        if err != nil {
+            var netErr net.Error
+            if errors.As(err, &netErr) && netErr.Timeout() {
+                if err := sub.Ping(ctx); err != nil {
+                    return true, err
+                }
+                continue
+            }
+            return true, err
+        }
+
+        if m, ok := msg.(*redis.Message); ok {
+            if err := handler(ctx, m.Channel, m.Payload); err != nil {
+                log.Printf("[eventbus] handler failed for message on %s: %v", m.Channel, err)
+            }
+        }
+    }
+}
+
+// Connected reports whether every subscription is currently live.
+func (b *RedisPubSub) Connected() bool {
+    return b.health.Connected()
+}
+
+// Close closes the underlying Redis client.
+func (b *RedisPubSub) Close() error {
+    return b.client.Close()
+}
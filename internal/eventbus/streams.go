@@ -0,0 +1,273 @@
+package eventbus
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "strings"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// streamPayloadField is the stream entry field that carries the message.
+const streamPayloadField = "payload"
+
+// StreamOptions configures the Redis Streams transport.
+type StreamOptions struct {
+    Group        string        // Consumer group shared by all replicas
+    Consumer     string        // This replica's consumer name within the group
+    MaxLen       int64         // Approximate stream length cap applied on XADD (0 = unbounded)
+    BatchSize    int64         // Entries read per XREADGROUP call
+    Block        time.Duration // How long XREADGROUP waits for new entries
+    ClaimMinIdle time.Duration // Pending entries idle this long are reclaimed from other consumers
+}
+
+// DefaultStreamOptions returns sensible defaults for the given consumer name.
+func DefaultStreamOptions(consumer string) StreamOptions {
+    return StreamOptions{
+        Group:        "licenseplate-plugin",
+        Consumer:     consumer,
+        MaxLen:       100000,
+        BatchSize:    10,
+        Block:        5 * time.Second,
+        ClaimMinIdle: time.Minute,
+    }
+}
+
+// RedisStreams is an EventBus backed by Redis Streams. Each channel is a
+// stream consumed through a consumer group, so entries survive restarts
+// and are handled by exactly one replica of the group.
+type RedisStreams struct {
+    client *redis.Client
+    opts   StreamOptions
+    health linkHealth
+}
+
+// NewRedisStreams creates a Streams bus on client. The bus owns the client
+// and closes it in Close.
+func NewRedisStreams(client *redis.Client, opts StreamOptions) *RedisStreams {
+    return &RedisStreams{client: client, opts: opts}
+}
+
+// Publish appends a message to the stream named channel with XADD. Unlike
+// PUB/SUB, the entry is retained until trimmed, so consumers that are
+// offline receive it when they come back.
+func (b *RedisStreams) Publish(ctx context.Context, channel, message string) error {
+    return b.client.XAdd(ctx, &redis.XAddArgs{
+        Stream: channel,
+        MaxLen: b.opts.MaxLen,
+        Approx: true,
+        Values: map[string]interface{}{streamPayloadField: message},
+    }).Err()
+}
+
+// Subscribe consumes the stream named channel as a member of the consumer
+// group. An entry is acknowledged only when the handler returns nil;
+// otherwise it stays pending and is redelivered once it has been idle for
+// ClaimMinIdle, to this or another replica. On start the consumer first
+// replays its own unacknowledged entries. Read errors are retried with
+// exponential backoff until ctx is cancelled.
+func (b *RedisStreams) Subscribe(ctx context.Context, channel string, handler Handler) error {
+    c := &streamConsumer{
+        client:  b.client,
+        stream:  channel,
+        opts:    b.opts,
+        handler: handler,
+        link:    b.health.register(channel),
+    }
+    go c.run(ctx)
+    return nil
+}
+
+// Connected reports whether every consumer is currently reading.
+func (b *RedisStreams) Connected() bool {
+    return b.health.Connected()
+}
+
+// Close closes the underlying Redis client.
+func (b *RedisStreams) Close() error {
+    return b.client.Close()
+}
+
+// ensureGroup creates the consumer group (and stream) if it does not exist.
+// New groups start at "$" so only entries added from now on are delivered.
+func ensureGroup(ctx context.Context, client *redis.Client, stream, group string) error {
+    err := client.XGroupCreateMkStream(ctx, stream, group, "$").Err()
+    if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+        return err
+    }
+    return nil
+}
+
+type streamConsumer struct {
+    client  *redis.Client
+    stream  string
+    opts    StreamOptions
+    handler Handler
+    link    *link
+}
+
+func (c *streamConsumer) run(ctx context.Context) {
+    defer c.link.close()
+    log.Printf("[eventbus] consuming stream %s as %s/%s", c.stream, c.opts.Group, c.opts.Consumer)
+
+    delay := minReconnectDelay
+    for {
+        connected, err := c.start(ctx)
+        if err == nil {
+            // start only returns nil once ctx is done
+            log.Printf("[eventbus] context canceled, stopped consuming %s", c.stream)
+            return
+        }
+        if connected {
+            delay = minReconnectDelay
+        }
+        c.link.down(err, delay)
+        if !sleepCtx(ctx, delay) {
+            return
+        }
+        delay = nextDelay(delay)
+    }
+}
+
+// start prepares the group, replays pending entries and then reads new
+// ones until a Redis error occurs, which it returns, or ctx is done. It
+// reports whether the consumer got as far as reading.
+func (c *streamConsumer) start(ctx context.Context) (bool, error) {
+    if err := ensureGroup(ctx, c.client, c.stream, c.opts.Group); err != nil {
+        return false, err
+    }
+    // "0" re-reads entries delivered to this consumer but never acknowledged
+    if err := c.readPending(ctx); err != nil {
+        return false, err
+    }
+    c.link.up()
+
+    lastClaim := time.Now()
+    for ctx.Err() == nil {
+        if time.Since(lastClaim) >= c.opts.ClaimMinIdle/2 {
+            c.reclaim(ctx)
+            c.pruneConsumers(ctx)
+            lastClaim = time.Now()
+        }
+
+        streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+            Group:    c.opts.Group,
+            Consumer: c.opts.Consumer,
+            Streams:  []string{c.stream, ">"},
+            Count:    c.opts.BatchSize,
+            Block:    c.opts.Block,
+        }).Result()
+        if errors.Is(err, redis.Nil) {
+            continue
+        }
+        if err != nil {
+            if ctx.Err() != nil {
+                return true, nil
+            }
+            return true, err
+        }
+
+        for _, s := range streams {
+            c.handle(ctx, s.Messages)
+        }
+    }
+    return true, nil
+}
+
+// readPending replays this consumer's pending entries from a previous run.
+func (c *streamConsumer) readPending(ctx context.Context) error {
+    streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+        Group:    c.opts.Group,
+        Consumer: c.opts.Consumer,
+        Streams:  []string{c.stream, "0"},
+        Count:    c.opts.BatchSize * 100,
+        Block:    -1,
+    }).Result()
+    if err != nil && !errors.Is(err, redis.Nil) {
+        return fmt.Errorf("read pending entries: %w", err)
+    }
+    for _, s := range streams {
+        c.handle(ctx, s.Messages)
+    }
+    return nil
+}
+
+// reclaim takes over entries that other consumers (e.g. crashed replicas)
+// received but did not acknowledge within ClaimMinIdle.
+func (c *streamConsumer) reclaim(ctx context.Context) {
+    start := "0-0"
+    for ctx.Err() == nil {
+        messages, next, err := c.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+            Stream:   c.stream,
+            Group:    c.opts.Group,
+            Consumer: c.opts.Consumer,
+            MinIdle:  c.opts.ClaimMinIdle,
+            Start:    start,
+            Count:    c.opts.BatchSize,
+        }).Result()
+        if err != nil {
+            if !errors.Is(err, redis.Nil) {
+                log.Printf("[eventbus] XAUTOCLAIM %s failed: %v", c.stream, err)
+            }
+            return
+        }
+        if len(messages) > 0 {
+            log.Printf("[eventbus] reclaimed %d pending entries from %s", len(messages), c.stream)
+            c.handle(ctx, messages)
+        }
+        if next == "0-0" || next == "" {
+            return
+        }
+        start = next
+    }
+}
+
+// pruneConsumers deletes consumers that have nothing pending and have not
+// read for ClaimMinIdle. Consumer names default to hostname-pid, so every
+// restart leaves one behind; once reclaim has taken over its entries it is
+// safe to drop. A live consumer reads at least every Block, so it is never
+// idle that long, and one deleted anyway is recreated by its next read.
+func (c *streamConsumer) pruneConsumers(ctx context.Context) {
+    consumers, err := c.client.XInfoConsumers(ctx, c.stream, c.opts.Group).Result()
+    if err != nil {
+        log.Printf("[eventbus] XINFO CONSUMERS %s failed: %v", c.stream, err)
+        return
+    }
+    for _, consumer := range consumers {
+        if consumer.Name == c.opts.Consumer || consumer.Pending > 0 || consumer.Idle < c.opts.ClaimMinIdle {
+            continue
+        }
+        if err := c.client.XGroupDelConsumer(ctx, c.stream, c.opts.Group, consumer.Name).Err(); err != nil {
+            log.Printf("[eventbus] XGROUP DELCONSUMER %s %s failed: %v", c.stream, consumer.Name, err)
+            continue
+        }
+        log.Printf("[eventbus] deleted idle consumer %s from %s/%s after %s", consumer.Name, c.stream, c.opts.Group, consumer.Idle)
+    }
+}
+
+func (c *streamConsumer) handle(ctx context.Context, messages []redis.XMessage) {
+    for _, msg := range messages {
+        payload, ok := msg.Values[streamPayloadField].(string)
+        if !ok {
+            // Not ours to interpret; ack so it does not circulate forever
+            log.Printf("[eventbus] stream entry %s has no %s field, acknowledging", msg.ID, streamPayloadField)
+            c.ack(ctx, msg.ID)
+            continue
+        }
+
+        if err := c.handler(ctx, c.stream, payload); err != nil {
+            log.Printf("[eventbus] handler failed for stream entry %s, leaving pending: %v", msg.ID, err)
+            continue
+        }
+        c.ack(ctx, msg.ID)
+    }
+}
+
+func (c *streamConsumer) ack(ctx context.Context, id string) {
+    if err := c.client.XAck(ctx, c.stream, c.opts.Group, id).Err(); err != nil {
+        log.Printf("[eventbus] XACK %s %s failed: %v", c.stream, id, err)
+    }
+}
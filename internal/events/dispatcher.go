@@ -3,41 +3,263 @@ package events
 import (
     "context"
     "encoding/json"
+    "errors"
+    "fmt"
     "log"
+    "slices"
+    "strings"
+    "sync"
 
-    "licenseplate-plugin/internal/handlers"
+    "licenseplate-plugin/internal/models"
     "licenseplate-plugin/internal/services"
 )
 
-// Event is the minimal wrapper expected on the bus
-type Event struct {
-    Type   string          `json:"type"`
+// Handler processes one event received on the bus.
+type Handler func(ctx context.Context, ev *models.EventEnvelope) error
+
+// Middleware wraps the handler registered under name, e.g. to log, recover
+// or measure it.
+type Middleware func(name string, next Handler) Handler
+
+// Typed adapts a handler that takes the event's data decoded into T.
+func Typed[T any](fn func(ctx context.Context, ev *models.EventEnvelope, data T) error) Handler {
+    return func(ctx context.Context, ev *models.EventEnvelope) error {
+        var data T
+        if err := json.Unmarshal(ev.Data, &data); err != nil {
+            return fmt.Errorf("invalid %s payload: %w", ev.Type, err)
+        }
+        return fn(ctx, ev, data)
+    }
+}
+
+// Registry routes events to the handlers registered for their type.
+// Packages register handlers at startup, so new event types (e.g. from a
+// PMS plugin) are wired in without changing the dispatcher.
+type Registry struct {
+    mu         sync.RWMutex
+    handlers   map[string][]registration
+    middleware  []Middleware
+    ownSource   string
+    deadLetters DeadLetterStore
+}
+
+// DeadLetterStore keeps messages that failed to dispatch.
+type DeadLetterStore interface {
+    RecordDeadLetter(ctx context.Context, dl *models.DeadLetter) error
+}
+
+type registration struct {
+    name      string
+    handler   Handler
+    acceptOwn bool
+}
+
+// HandlerOption configures a registered handler.
+type HandlerOption func(*registration)
+
+// AcceptOwnEvents lets a handler receive events this plugin published
+// itself, which the registry otherwise skips (see SkipOwnEvents).
+func AcceptOwnEvents() HandlerOption {
+    return func(r *registration) {
+        r.acceptOwn = true
+    }
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+    return &Registry{handlers: make(map[string][]registration)}
+}
+
+// Use appends middleware. The first middleware added is the outermost.
+// It applies to handlers registered afterwards.
+func (r *Registry) Use(mw ...Middleware) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.middleware = append(r.middleware, mw...)
+}
+
+// SkipOwnEvents makes Dispatch skip events whose source is source or lies
+// under it (source + "/..."), so the plugin does not re-process what it
+// published itself, on this or any other replica. Handlers registered with
+// AcceptOwnEvents still receive them.
+func (r *Registry) SkipOwnEvents(source string) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.ownSource = source
+}
+
+// SetDeadLetterStore makes Dispatch keep failed messages in store instead
+// of dropping or returning them.
+func (r *Registry) SetDeadLetterStore(store DeadLetterStore) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.deadLetters = store
+}
+
+// Register adds a handler for eventType under name, which identifies it in
+// logs and metrics. Several handlers may be registered for one type; they
+// run in registration order.
+func (r *Registry) Register(eventType, name string, h Handler, opts ...HandlerOption) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    for i := len(r.middleware) - 1; i >= 0; i-- {
+        h = r.middleware[i](name, h)
+    }
+    reg := registration{name: name, handler: h}
+    for _, opt := range opts {
+        opt(&reg)
+    }
+    r.handlers[eventType] = append(r.handlers[eventType], reg)
+}
+
+// isOwn reports whether ev was published by this plugin.
+func (r *Registry) isOwn(ev *models.EventEnvelope) bool {
+    return r.ownSource != "" && (ev.Source == r.ownSource || strings.HasPrefix(ev.Source, r.ownSource+"/"))
+}
+
+// Types returns the event types with at least one handler.
+func (r *Registry) Types() []string {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    types := make([]string, 0, len(r.handlers))
+    for t := range r.handlers {
+        types = append(types, t)
+    }
+    return types
+}
+
+// wireEvent is the union of the two shapes accepted on the bus: the
+// CloudEvents envelope and the legacy {"type","record"} wrapper.
+type wireEvent struct {
+    models.EventEnvelope
     Record json.RawMessage `json:"record"`
 }
 
-// Dispatch parses a raw message and routes it to the correct handler.
-// It runs handler calls asynchronously so the caller (listener) is not blocked.
-func Dispatch(ctx context.Context, svc *services.LicensePlateService, rawMessage string) {
-    var ev Event
-    log.Printf("[events] dispatching raw message: %s", rawMessage)
-    if err := json.Unmarshal([]byte(rawMessage), &ev); err != nil {
-        log.Printf("[events] invalid event JSON: %v", err)
-        return
+// ParseEvent decodes a bus message in either the CloudEvents envelope or the
+// legacy {"type","record"} shape. A legacy event is returned as an envelope
+// with an empty SpecVersion and its record as Data.
+func ParseEvent(rawMessage []byte) (*models.EventEnvelope, error) {
+    var w wireEvent
+    if err := json.Unmarshal(rawMessage, &w); err != nil {
+        return nil, err
     }
+    if w.Type == "" {
+        return nil, errors.New("missing event type")
+    }
+    if w.SpecVersion == "" {
+        w.Data = w.Record
+    }
+    return &w.EventEnvelope, nil
+}
 
-    log.Printf("[events] parsed event type=%s", ev.Type)
+// Dispatch parses a raw message received on channel and runs the handlers
+// registered for its type synchronously, skipping the plugin's own events
+// as configured by SkipOwnEvents. Messages that cannot be parsed, have no
+// handler, or fail in a handler are stored as dead letters when a store is
+// set (see SetDeadLetterStore) and then count as handled. Without a store,
+// invalid JSON and unknown types are only logged, since retrying cannot fix
+// them, and handler errors are returned so transports with
+// acknowledgements (Redis Streams) only ack messages that were processed.
+// An error storing a dead letter is returned as well.
+func (r *Registry) Dispatch(ctx context.Context, channel, rawMessage string) error {
+    _, err := r.dispatch(ctx, channel, rawMessage, "", false)
+    return err
+}
+
+// Redispatch runs a dead-lettered message again: only the handler that
+// failed, or all handlers for its type when none is named. Own events are
+// not skipped. A new failure updates the dead letter and is returned.
+func (r *Registry) Redispatch(ctx context.Context, dl *models.DeadLetter) error {
+    failure, err := r.dispatch(ctx, dl.Channel, dl.Payload, dl.Handler, true)
+    return errors.Join(failure, err)
+}
 
-    switch ev.Type {
-    case "licenseplate.scanned":
-        log.Printf("[events] routing to HandleLicenseplateScanned")
-        go func() {
-            if err := handlers.HandleLicenseplateScanned(svc, ctx, ev.Record); err != nil {
-                log.Printf("[events] licenseplate.scanned handler error: %v", err)
-            } else {
-                log.Printf("[events] licenseplate.scanned handled successfully")
+// dispatch does the work of Dispatch and Redispatch. It returns what went
+// wrong processing the message, if anything, and the error the transport
+// should see.
+func (r *Registry) dispatch(ctx context.Context, channel, rawMessage, only string, force bool) (failure, err error) {
+    ev, parseErr := ParseEvent([]byte(rawMessage))
+    if parseErr != nil {
+        log.Printf("[events] invalid event: %v: %s", parseErr, rawMessage)
+        failure = fmt.Errorf("invalid event: %w", parseErr)
+        return failure, r.deadLetter(ctx, channel, rawMessage, nil, "", failure, false)
+    }
+
+    r.mu.RLock()
+    regs := r.handlers[ev.Type]
+    own := !force && r.isOwn(ev)
+    r.mu.RUnlock()
+    if own {
+        regs = slices.DeleteFunc(slices.Clone(regs), func(reg registration) bool { return !reg.acceptOwn })
+        if len(regs) == 0 {
+            log.Printf("[events] skipping own event type=%s id=%s source=%s", ev.Type, ev.ID, ev.Source)
+            return nil, nil
+        }
+    }
+    if only != "" {
+        regs = slices.DeleteFunc(slices.Clone(regs), func(reg registration) bool { return reg.name != only })
+        if len(regs) == 0 {
+            failure = fmt.Errorf("handler %s is not registered for %s", only, ev.Type)
+            return failure, r.deadLetter(ctx, channel, rawMessage, ev, only, failure, false)
+        }
+    }
+    if len(regs) == 0 {
+        log.Printf("[events] no handler for event type: %s", ev.Type)
+        failure = fmt.Errorf("no handler for event type %s", ev.Type)
+        return failure, r.deadLetter(ctx, channel, rawMessage, ev, "", failure, false)
+    }
+
+    // Events emitted while handling this one carry the same correlation id
+    if ev.CorrelationID != "" {
+        ctx = services.WithCorrelationID(ctx, ev.CorrelationID)
+    }
+
+    var failures, errs []error
+    for _, reg := range regs {
+        if herr := reg.handler(ctx, ev); herr != nil {
+            herr = fmt.Errorf("%s: %w", reg.name, herr)
+            failures = append(failures, herr)
+            if dlErr := r.deadLetter(ctx, channel, rawMessage, ev, reg.name, herr, true); dlErr != nil {
+                errs = append(errs, dlErr)
             }
-        }()
-    default:
-        log.Printf("[events] unknown event type: %s", ev.Type)
+        }
+    }
+    return errors.Join(failures...), errors.Join(errs...)
+}
+
+// deadLetter stores a failed message if a dead letter store is set. Without
+// one it returns cause when the failure is retryable and nil otherwise,
+// which is what Dispatch reports to the transport.
+func (r *Registry) deadLetter(ctx context.Context, channel, rawMessage string, ev *models.EventEnvelope, handler string, cause error, retryable bool) error {
+    r.mu.RLock()
+    store := r.deadLetters
+    r.mu.RUnlock()
+    if store == nil {
+        if retryable {
+            return cause
+        }
+        return nil
+    }
+
+    dl := &models.DeadLetter{
+        Channel: channel,
+        Handler: handler,
+        Payload: rawMessage,
+        Error:   cause.Error(),
+    }
+    if ev != nil {
+        dl.EventID, dl.EventType = ev.ID, ev.Type
+    }
+    if dl.EventID == "" {
+        dl.EventID = services.DerivedEventID(channel, rawMessage)
+    }
+
+    // Record the failure even if it was caused by the context ending
+    if err := store.RecordDeadLetter(context.WithoutCancel(ctx), dl); err != nil {
+        log.Printf("[events] storing dead letter failed: %v", err)
+        return errors.Join(cause, fmt.Errorf("store dead letter: %w", err))
     }
+    log.Printf("[events] dead-lettered message %d (event %s, handler %q, attempt %d): %v",
+        dl.ID, dl.EventID, handler, dl.Attempts, cause)
+    return nil
 }
@@ -0,0 +1,211 @@
+package events
+
+import (
+    "context"
+    "errors"
+    "slices"
+    "strings"
+    "sync"
+    "testing"
+
+    "licenseplate-plugin/internal/models"
+)
+
+func TestParseEvent(t *testing.T) {
+    tests := []struct {
+        name     string
+        raw      string
+        wantType string
+        wantSpec string
+        wantID   string
+        wantData string
+        wantErr  bool
+    }{
+        {
+            name:     "cloudevents envelope",
+            raw:      `{"specversion":"1.0","id":"evt-1","source":"pms","type":"guest.checked_in","data":{"room":"101"}}`,
+            wantType: "guest.checked_in",
+            wantSpec: "1.0",
+            wantID:   "evt-1",
+            wantData: `{"room":"101"}`,
+        },
+        {
+            name:     "legacy type and record",
+            raw:      `{"type":"license_plate.scanned","record":{"plate_number":"ABC123"}}`,
+            wantType: "license_plate.scanned",
+            wantData: `{"plate_number":"ABC123"}`,
+        },
+        {
+            // Only the legacy shape takes its data from record
+            name:     "envelope ignores record",
+            raw:      `{"specversion":"1.0","id":"evt-2","type":"guest.checked_in","data":{"room":"202"},"record":{"room":"999"}}`,
+            wantType: "guest.checked_in",
+            wantSpec: "1.0",
+            wantID:   "evt-2",
+            wantData: `{"room":"202"}`,
+        },
+        {
+            name:    "missing type",
+            raw:     `{"specversion":"1.0","id":"evt-3","data":{}}`,
+            wantErr: true,
+        },
+        {
+            name:    "legacy without type",
+            raw:     `{"record":{"plate_number":"ABC123"}}`,
+            wantErr: true,
+        },
+        {
+            name:    "malformed json",
+            raw:     `{"type":"guest.checked_in",`,
+            wantErr: true,
+        },
+        {
+            name:    "not an object",
+            raw:     `["guest.checked_in"]`,
+            wantErr: true,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ev, err := ParseEvent([]byte(tt.raw))
+            if tt.wantErr {
+                if err == nil {
+                    t.Fatalf("ParseEvent = %+v, want an error", ev)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("ParseEvent: %v", err)
+            }
+            if ev.Type != tt.wantType || ev.SpecVersion != tt.wantSpec || ev.ID != tt.wantID {
+                t.Errorf("type, specversion, id = %q, %q, %q; want %q, %q, %q", ev.Type, ev.SpecVersion, ev.ID, tt.wantType, tt.wantSpec, tt.wantID)
+            }
+            if string(ev.Data) != tt.wantData {
+                t.Errorf("data = %s, want %s", ev.Data, tt.wantData)
+            }
+        })
+    }
+}
+
+// recordingStore keeps the dead letters a registry records.
+type recordingStore struct {
+    mu          sync.Mutex
+    deadLetters []models.DeadLetter
+}
+
+func (s *recordingStore) RecordDeadLetter(ctx context.Context, dl *models.DeadLetter) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.deadLetters = append(s.deadLetters, *dl)
+    return nil
+}
+
+func TestRegistryMiddlewareOrder(t *testing.T) {
+    var calls []string
+    trace := func(label string) Middleware {
+        return func(name string, next Handler) Handler {
+            return func(ctx context.Context, ev *models.EventEnvelope) error {
+                calls = append(calls, label+" before "+name)
+                err := next(ctx, ev)
+                calls = append(calls, label+" after "+name)
+                return err
+            }
+        }
+    }
+
+    r := NewRegistry()
+    r.Use(trace("outer"), trace("middle"))
+    r.Use(trace("inner"))
+    r.Register("test.event", "wrapped", func(ctx context.Context, ev *models.EventEnvelope) error {
+        calls = append(calls, "handler")
+        return nil
+    })
+    // Middleware added later only wraps handlers registered after it
+    r.Use(trace("late"))
+
+    if err := r.Dispatch(context.Background(), "events", message("test.event", 1)); err != nil {
+        t.Fatalf("dispatch: %v", err)
+    }
+    want := []string{
+        "outer before wrapped", "middle before wrapped", "inner before wrapped",
+        "handler",
+        "inner after wrapped", "middle after wrapped", "outer after wrapped",
+    }
+    if !slices.Equal(calls, want) {
+        t.Errorf("calls = %q, want %q", calls, want)
+    }
+}
+
+func TestRegistryRunsEveryHandlerForType(t *testing.T) {
+    var calls []string
+    handler := func(name string, err error) Handler {
+        return func(ctx context.Context, ev *models.EventEnvelope) error {
+            calls = append(calls, name)
+            return err
+        }
+    }
+    failure := errors.New("projection unavailable")
+
+    r := NewRegistry()
+    r.Register("test.event", "first", handler("first", nil))
+    r.Register("test.event", "second", handler("second", failure))
+    r.Register("test.event", "third", handler("third", nil))
+    r.Register("other.event", "other", handler("other", nil))
+
+    // A failing handler does not stop the ones after it, and its error
+    // names it
+    err := r.Dispatch(context.Background(), "events", message("test.event", 1))
+    if !errors.Is(err, failure) || !strings.Contains(err.Error(), "second") {
+        t.Errorf("dispatch = %v, want the second handler's failure", err)
+    }
+    if want := []string{"first", "second", "third"}; !slices.Equal(calls, want) {
+        t.Errorf("calls = %q, want %q", calls, want)
+    }
+
+    types := r.Types()
+    slices.Sort(types)
+    if want := []string{"other.event", "test.event"}; !slices.Equal(types, want) {
+        t.Errorf("types = %q, want %q", types, want)
+    }
+}
+
+func TestTypedDecodesData(t *testing.T) {
+    type guest struct {
+        Room string `json:"room"`
+    }
+    var rooms []string
+    store := &recordingStore{}
+    r := NewRegistry()
+    r.SetDeadLetterStore(store)
+    r.Register("guest.checked_in", "rooms", Typed(func(ctx context.Context, ev *models.EventEnvelope, g guest) error {
+        rooms = append(rooms, g.Room)
+        return nil
+    }))
+
+    ctx := context.Background()
+    valid := `{"specversion":"1.0","id":"evt-1","type":"guest.checked_in","data":{"room":"101"}}`
+    if err := r.Dispatch(ctx, "events", valid); err != nil {
+        t.Fatalf("dispatch: %v", err)
+    }
+    if !slices.Equal(rooms, []string{"101"}) {
+        t.Fatalf("rooms = %q, want [101]", rooms)
+    }
+
+    // Data that does not decode into the handler's type never reaches it
+    // and is dead-lettered against the handler
+    invalid := `{"specversion":"1.0","id":"evt-2","type":"guest.checked_in","data":{"room":101}}`
+    if err := r.Dispatch(ctx, "events", invalid); err != nil {
+        t.Fatalf("dispatch with a dead letter store: %v", err)
+    }
+    if len(rooms) != 1 {
+        t.Errorf("handler ran on undecodable data: rooms = %q", rooms)
+    }
+    if len(store.deadLetters) != 1 {
+        t.Fatalf("dead letters = %+v, want one", store.deadLetters)
+    }
+    dl := store.deadLetters[0]
+    if dl.EventID != "evt-2" || dl.Handler != "rooms" || !strings.Contains(dl.Error, "invalid guest.checked_in payload") {
+        t.Errorf("dead letter = %+v, want evt-2 failed in rooms with a payload error", dl)
+    }
+}
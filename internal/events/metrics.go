@@ -0,0 +1,84 @@
+package events
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "time"
+
+    "licenseplate-plugin/internal/models"
+)
+
+// HandlerStats counts the invocations of one handler for one event type.
+type HandlerStats struct {
+    EventType   string     `json:"event_type"`
+    Handler     string     `json:"handler"`
+    Handled     int64      `json:"handled"`
+    Failed      int64      `json:"failed"`
+    Panics      int64      `json:"panics"`
+    TotalMillis float64    `json:"total_ms"`
+    LastError   string     `json:"last_error,omitempty"`
+    LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Metrics collects per-handler counters in memory.
+type Metrics struct {
+    mu    sync.Mutex
+    stats map[[2]string]*HandlerStats
+}
+
+// NewMetrics creates an empty metrics collector.
+func NewMetrics() *Metrics {
+    return &Metrics{stats: make(map[[2]string]*HandlerStats)}
+}
+
+// Middleware records each invocation of the wrapped handler. Install it
+// outside Recovery so panics are counted.
+func (m *Metrics) Middleware() Middleware {
+    return func(name string, next Handler) Handler {
+        return func(ctx context.Context, ev *models.EventEnvelope) error {
+            start := time.Now()
+            err := next(ctx, ev)
+            m.record(ev.Type, name, time.Since(start), err)
+            return err
+        }
+    }
+}
+
+func (m *Metrics) record(eventType, name string, d time.Duration, err error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    key := [2]string{eventType, name}
+    s, ok := m.stats[key]
+    if !ok {
+        s = &HandlerStats{EventType: eventType, Handler: name}
+        m.stats[key] = s
+    }
+    s.TotalMillis += float64(d) / float64(time.Millisecond)
+    if err == nil {
+        s.Handled++
+        return
+    }
+
+    s.Failed++
+    var panicErr *PanicError
+    if errors.As(err, &panicErr) {
+        s.Panics++
+    }
+    now := time.Now()
+    s.LastError = err.Error()
+    s.LastErrorAt = &now
+}
+
+// Snapshot returns a copy of the current counters.
+func (m *Metrics) Snapshot() []HandlerStats {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    out := make([]HandlerStats, 0, len(m.stats))
+    for _, s := range m.stats {
+        out = append(out, *s)
+    }
+    return out
+}
@@ -0,0 +1,53 @@
+package events
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "runtime/debug"
+    "time"
+
+    "licenseplate-plugin/internal/models"
+)
+
+// PanicError is returned in place of a handler that panicked.
+type PanicError struct {
+    Value interface{}
+    Stack []byte
+}
+
+func (e *PanicError) Error() string {
+    return fmt.Sprintf("handler panicked: %v", e.Value)
+}
+
+// Recovery turns a panic in the handler into a *PanicError, so one bad
+// event cannot take the listener down.
+func Recovery() Middleware {
+    return func(name string, next Handler) Handler {
+        return func(ctx context.Context, ev *models.EventEnvelope) (err error) {
+            defer func() {
+                if v := recover(); v != nil {
+                    err = &PanicError{Value: v, Stack: debug.Stack()}
+                    log.Printf("[events] %s panicked on %s %s: %v\n%s", name, ev.Type, ev.ID, v, err.(*PanicError).Stack)
+                }
+            }()
+            return next(ctx, ev)
+        }
+    }
+}
+
+// Logging logs each handler invocation with its outcome and duration.
+func Logging() Middleware {
+    return func(name string, next Handler) Handler {
+        return func(ctx context.Context, ev *models.EventEnvelope) error {
+            start := time.Now()
+            err := next(ctx, ev)
+            if err != nil {
+                log.Printf("[events] %s failed on %s id=%s source=%s after %s: %v", name, ev.Type, ev.ID, ev.Source, time.Since(start), err)
+                return err
+            }
+            log.Printf("[events] %s handled %s id=%s source=%s in %s", name, ev.Type, ev.ID, ev.Source, time.Since(start))
+            return nil
+        }
+    }
+}
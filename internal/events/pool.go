@@ -0,0 +1,288 @@
+package events
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "licenseplate-plugin/internal/models"
+)
+
+// OverflowPolicy decides what Submit does when the queue is full.
+type OverflowPolicy string
+
+const (
+    OverflowBlock OverflowPolicy = "block" // Wait for room; backpressure reaches the bus
+    OverflowDrop  OverflowPolicy = "drop"  // Discard the message and log it
+    OverflowSpill OverflowPolicy = "spill" // Park the message in inbound_event_spill
+)
+
+// ParseOverflowPolicy validates a policy name.
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+    switch p := OverflowPolicy(s); p {
+    case OverflowBlock, OverflowDrop, OverflowSpill:
+        return p, nil
+    }
+    return "", fmt.Errorf("unknown overflow policy %q (expected block, drop or spill)", s)
+}
+
+// ErrPoolClosed is returned by Submit after Shutdown.
+var ErrPoolClosed = errors.New("events: worker pool is shut down")
+
+// SpillStore parks messages for OverflowSpill.
+type SpillStore interface {
+    SpillInboundEvent(ctx context.Context, channel, payload string) error
+    TakeSpilledInboundEvents(ctx context.Context, limit int) ([]models.SpilledEvent, error)
+}
+
+// PoolConfig sizes a Pool.
+type PoolConfig struct {
+    Workers   int            // Handlers running concurrently
+    QueueSize int            // Messages waiting for a worker
+    Overflow  OverflowPolicy // What to do when the queue is full
+}
+
+// PoolStats reports the pool's current state.
+type PoolStats struct {
+    Workers   int            `json:"workers"`
+    Queued    int            `json:"queued"`
+    QueueSize int            `json:"queue_size"`
+    Overflow  OverflowPolicy `json:"overflow"`
+    Dropped   int64          `json:"dropped"`
+    Spilled   int64          `json:"spilled"`
+}
+
+// Pool dispatches bus messages on a fixed number of workers fed by a
+// bounded queue, so a burst cannot start an unbounded number of handlers.
+type Pool struct {
+    registry *Registry
+    config   PoolConfig
+    spill    SpillStore
+
+    queue      chan job
+    wg         sync.WaitGroup
+    ctx        context.Context // Passed to handlers; cancelled if Shutdown times out
+    cancel     context.CancelFunc
+    refillCtx  context.Context // Stops the spill refill loop
+    stopRefill context.CancelFunc
+
+    mu          sync.RWMutex  // Guards closed and sends on queue
+    closed      bool
+    closing     chan struct{} // Closed when Shutdown starts, to release blocked submitters
+    closingOnce sync.Once
+
+    dropped atomic.Int64
+    spilled atomic.Int64
+}
+
+type job struct {
+    channel string
+    message string
+    done    chan error // Receives the result when the submitter waits; may be nil
+}
+
+// NewPool creates a pool dispatching through registry and starts its
+// workers. spill is required for OverflowSpill.
+func NewPool(registry *Registry, config PoolConfig, spill SpillStore) *Pool {
+    config.Workers = max(config.Workers, 1)
+    config.QueueSize = max(config.QueueSize, 0)
+    if config.Overflow == OverflowSpill && spill == nil {
+        log.Println("[events] spill overflow needs a spill store, blocking instead")
+        config.Overflow = OverflowBlock
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    refillCtx, stopRefill := context.WithCancel(ctx)
+    p := &Pool{
+        registry:   registry,
+        config:     config,
+        spill:      spill,
+        queue:      make(chan job, config.QueueSize),
+        closing:    make(chan struct{}),
+        ctx:        ctx,
+        cancel:     cancel,
+        refillCtx:  refillCtx,
+        stopRefill: stopRefill,
+    }
+
+    for i := 0; i < config.Workers; i++ {
+        p.wg.Add(1)
+        go p.work()
+    }
+    if config.Overflow == OverflowSpill {
+        p.wg.Add(1)
+        go p.refill()
+    }
+    return p
+}
+
+// work runs jobs until the queue is closed by Shutdown.
+func (p *Pool) work() {
+    defer p.wg.Done()
+    for j := range p.queue {
+        err := p.dispatch(j)
+        if j.done != nil {
+            j.done <- err
+        }
+    }
+}
+
+// dispatch runs one message, turning a panic outside the handler
+// middleware (e.g. in parsing) into an error.
+func (p *Pool) dispatch(j job) (err error) {
+    defer func() {
+        if v := recover(); v != nil {
+            err = fmt.Errorf("dispatch panicked: %v", v)
+            log.Printf("[events] %v", err)
+        }
+    }()
+    return p.registry.Dispatch(p.ctx, j.channel, j.message)
+}
+
+// Submit queues a message without waiting for it to be handled. When the
+// queue is full it applies the overflow policy. It can be used directly as
+// an eventbus.Handler.
+func (p *Pool) Submit(ctx context.Context, channel, message string) error {
+    return p.enqueue(ctx, job{channel: channel, message: message}, p.config.Overflow)
+}
+
+// Process queues a message and waits for its handlers to finish, returning
+// their error. Use it for transports that acknowledge messages (Redis
+// Streams) so a message is only acked once handled; the queue then blocks
+// rather than dropping or spilling, since the bus keeps unacked messages.
+func (p *Pool) Process(ctx context.Context, channel, message string) error {
+    done := make(chan error, 1)
+    if err := p.enqueue(ctx, job{channel: channel, message: message, done: done}, OverflowBlock); err != nil {
+        return err
+    }
+    select {
+    case err := <-done:
+        return err
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+func (p *Pool) enqueue(ctx context.Context, j job, policy OverflowPolicy) error {
+    p.mu.RLock()
+    defer p.mu.RUnlock()
+    if p.closed {
+        return ErrPoolClosed
+    }
+
+    select {
+    case p.queue <- j:
+        return nil
+    default:
+    }
+
+    switch policy {
+    case OverflowDrop:
+        p.dropped.Add(1)
+        log.Printf("[events] queue full, dropping message on %s: %s", j.channel, j.message)
+        return nil
+    case OverflowSpill:
+        if err := p.spill.SpillInboundEvent(ctx, j.channel, j.message); err != nil {
+            p.dropped.Add(1)
+            log.Printf("[events] queue full and spill failed, dropping message on %s: %v", j.channel, err)
+            return nil
+        }
+        p.spilled.Add(1)
+        return nil
+    default:
+        // Shutdown needs the write lock to close the queue, so a submitter
+        // blocked here under the read lock must give up when it starts
+        select {
+        case p.queue <- j:
+            return nil
+        case <-p.closing:
+            return ErrPoolClosed
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+}
+
+// refill feeds spilled messages back into the queue while it has room.
+func (p *Pool) refill() {
+    defer p.wg.Done()
+    ticker := time.NewTicker(time.Second)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-p.refillCtx.Done():
+            return
+        case <-ticker.C:
+        }
+
+        // Leave half the queue for live traffic
+        room := cap(p.queue)/2 - len(p.queue)
+        if room <= 0 {
+            continue
+        }
+        spilled, err := p.spill.TakeSpilledInboundEvents(p.refillCtx, room)
+        if err != nil {
+            log.Printf("[events] reading spilled messages failed: %v", err)
+            continue
+        }
+        for _, e := range spilled {
+            // Falls back to spilling again if live traffic filled the queue
+            err := p.Submit(p.ctx, e.Channel, e.Payload)
+            if errors.Is(err, ErrPoolClosed) {
+                // Shutting down; put it back for the next start
+                err = p.spill.SpillInboundEvent(p.ctx, e.Channel, e.Payload)
+            }
+            if err != nil {
+                log.Printf("[events] requeueing spilled message %d failed: %v", e.ID, err)
+            }
+        }
+    }
+}
+
+// Stats reports queue depth and overflow counters.
+func (p *Pool) Stats() PoolStats {
+    return PoolStats{
+        Workers:   p.config.Workers,
+        Queued:    len(p.queue),
+        QueueSize: cap(p.queue),
+        Overflow:  p.config.Overflow,
+        Dropped:   p.dropped.Load(),
+        Spilled:   p.spilled.Load(),
+    }
+}
+
+// Shutdown stops accepting messages and waits for queued and in-flight
+// handlers to finish. Submitters blocked on a full queue get ErrPoolClosed.
+// If ctx ends first, handlers' context is cancelled and Shutdown returns
+// ctx.Err() without waiting further.
+func (p *Pool) Shutdown(ctx context.Context) error {
+    p.stopRefill()
+    p.closingOnce.Do(func() { close(p.closing) })
+
+    p.mu.Lock()
+    if !p.closed {
+        p.closed = true
+        close(p.queue)
+    }
+    p.mu.Unlock()
+
+    done := make(chan struct{})
+    go func() {
+        p.wg.Wait()
+        close(done)
+    }()
+
+    select {
+    case <-done:
+        p.cancel()
+        return nil
+    case <-ctx.Done():
+        p.cancel()
+        return ctx.Err()
+    }
+}
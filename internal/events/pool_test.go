@@ -0,0 +1,159 @@
+package events
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "licenseplate-plugin/internal/models"
+)
+
+func message(eventType string, i int) string {
+    return fmt.Sprintf(`{"specversion":"1.0","id":"%d","type":%q,"data":{}}`, i, eventType)
+}
+
+func TestPoolFinishesQueuedHandlersOnShutdown(t *testing.T) {
+    var handled atomic.Int64
+    r := NewRegistry()
+    r.Register("test.event", "slow", func(ctx context.Context, ev *models.EventEnvelope) error {
+        time.Sleep(5 * time.Millisecond)
+        handled.Add(1)
+        return nil
+    })
+
+    p := NewPool(r, PoolConfig{Workers: 2, QueueSize: 50, Overflow: OverflowBlock}, nil)
+    for i := 0; i < 50; i++ {
+        if err := p.Submit(context.Background(), "events", message("test.event", i)); err != nil {
+            t.Fatalf("submit %d: %v", i, err)
+        }
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    if err := p.Shutdown(ctx); err != nil {
+        t.Fatalf("shutdown: %v", err)
+    }
+    if got := handled.Load(); got != 50 {
+        t.Errorf("handled %d events, want 50", got)
+    }
+    if err := p.Submit(context.Background(), "events", message("test.event", 0)); !errors.Is(err, ErrPoolClosed) {
+        t.Errorf("submit after shutdown = %v, want ErrPoolClosed", err)
+    }
+}
+
+func TestPoolRecoversHandlerPanics(t *testing.T) {
+    r := NewRegistry()
+    r.Use(Recovery())
+    r.Register("test.event", "panics", func(ctx context.Context, ev *models.EventEnvelope) error {
+        panic("boom")
+    })
+
+    p := NewPool(r, PoolConfig{Workers: 1, QueueSize: 1}, nil)
+    defer p.Shutdown(context.Background())
+
+    err := p.Process(context.Background(), "events", message("test.event", 1))
+    var panicErr *PanicError
+    if !errors.As(err, &panicErr) {
+        t.Fatalf("Process error = %v, want a PanicError", err)
+    }
+    // The worker survived and keeps processing
+    if err := p.Process(context.Background(), "events", message("other.event", 2)); err != nil {
+        t.Errorf("Process after panic: %v", err)
+    }
+}
+
+func TestPoolDropsWhenQueueIsFull(t *testing.T) {
+    started := make(chan struct{}, 1)
+    release := make(chan struct{})
+    r := NewRegistry()
+    r.Register("test.event", "blocked", func(ctx context.Context, ev *models.EventEnvelope) error {
+        select {
+        case started <- struct{}{}:
+        default:
+        }
+        <-release
+        return nil
+    })
+
+    p := NewPool(r, PoolConfig{Workers: 1, QueueSize: 2, Overflow: OverflowDrop}, nil)
+    // The first message occupies the worker, two fill the queue, the rest drop
+    if err := p.Submit(context.Background(), "events", message("test.event", 0)); err != nil {
+        t.Fatalf("submit: %v", err)
+    }
+    <-started
+    for i := 1; i < 10; i++ {
+        if err := p.Submit(context.Background(), "events", message("test.event", i)); err != nil {
+            t.Fatalf("submit %d: %v", i, err)
+        }
+    }
+    close(release)
+    if err := p.Shutdown(context.Background()); err != nil {
+        t.Fatalf("shutdown: %v", err)
+    }
+
+    if dropped := p.Stats().Dropped; dropped != 7 {
+        t.Errorf("dropped %d messages, want 7", dropped)
+    }
+}
+
+// TestPoolShutdownReleasesBlockedSubmit shuts down while a submitter waits
+// for room in a full queue behind a handler that only finishes afterwards.
+func TestPoolShutdownReleasesBlockedSubmit(t *testing.T) {
+    var handled atomic.Int64
+    started := make(chan struct{}, 1)
+    release := make(chan struct{})
+    r := NewRegistry()
+    r.Register("test.event", "blocked", func(ctx context.Context, ev *models.EventEnvelope) error {
+        select {
+        case started <- struct{}{}:
+        default:
+        }
+        <-release
+        handled.Add(1)
+        return nil
+    })
+
+    p := NewPool(r, PoolConfig{Workers: 1, QueueSize: 1, Overflow: OverflowBlock}, nil)
+    // The first message occupies the worker and the second fills the queue
+    if err := p.Submit(context.Background(), "events", message("test.event", 0)); err != nil {
+        t.Fatalf("submit: %v", err)
+    }
+    <-started
+    if err := p.Submit(context.Background(), "events", message("test.event", 1)); err != nil {
+        t.Fatalf("submit: %v", err)
+    }
+
+    blocked := make(chan error, 1)
+    go func() {
+        blocked <- p.Submit(context.Background(), "events", message("test.event", 2))
+    }()
+    // Give the third submit time to block on the full queue
+    time.Sleep(20 * time.Millisecond)
+
+    shutdown := make(chan error, 1)
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+        shutdown <- p.Shutdown(ctx)
+    }()
+
+    select {
+    case err := <-blocked:
+        if !errors.Is(err, ErrPoolClosed) {
+            t.Fatalf("blocked submit = %v, want ErrPoolClosed", err)
+        }
+    case <-time.After(2 * time.Second):
+        t.Fatal("blocked submit was not released by Shutdown")
+    }
+
+    close(release)
+    if err := <-shutdown; err != nil {
+        t.Fatalf("shutdown: %v", err)
+    }
+    if got := handled.Load(); got != 2 {
+        t.Errorf("handled %d events, want the 2 accepted before shutdown", got)
+    }
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"licenseplate-plugin/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CorrelationIDHeader carries the correlation id of a request. Events
+// enqueued while serving the request are stamped with it.
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// CorrelationID takes the correlation id from the request header, or
+// generates one, stores it on the request context and echoes it in the
+// response header.
+func CorrelationID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(CorrelationIDHeader)
+		if id == "" {
+			id = services.NewEventID()
+		}
+		c.Request = c.Request.WithContext(services.WithCorrelationID(c.Request.Context(), id))
+		c.Header(CorrelationIDHeader, id)
+		c.Next()
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/services"
+	"licenseplate-plugin/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultRotationOverlap is how long a rotated key keeps working when the
+// request does not say
+const defaultRotationOverlap = 24 * time.Hour
+
+// CredentialHandler manages the keys XPOTS cameras sign webhooks with
+type CredentialHandler struct {
+	service *services.LicensePlateService
+}
+
+func NewCredentialHandler(service *services.LicensePlateService) *CredentialHandler {
+	return &CredentialHandler{
+		service: service,
+	}
+}
+
+// IssueCredential creates a key for a site or camera. The secret is only
+// returned in this response.
+func (h *CredentialHandler) IssueCredential(c *gin.Context) {
+	var req models.WebhookCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cred, err := h.service.IssueWebhookCredential(c.Request.Context(), req)
+	if err != nil {
+		h.credentialError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, cred)
+}
+
+// ListCredentials lists keys, without secrets, filtered by site and camera
+func (h *CredentialHandler) ListCredentials(c *gin.Context) {
+	filter := storage.WebhookCredentialFilter{
+		Site:     c.Query("site"),
+		CameraID: c.Query("camera_id"),
+	}
+	if v := c.Query("include_inactive"); v != "" {
+		include, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "include_inactive must be true or false"})
+			return
+		}
+		filter.IncludeInactive = include
+	}
+
+	creds, err := h.service.ListWebhookCredentials(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhook_keys": creds,
+		"count":        len(creds),
+	})
+}
+
+// RotateCredential issues a replacement key. The old key keeps working for
+// the requested overlap so cameras can be reconfigured without downtime.
+func (h *CredentialHandler) RotateCredential(c *gin.Context) {
+	var req models.RotateCredentialRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	overlap := defaultRotationOverlap
+	if req.Overlap != "" {
+		d, err := time.ParseDuration(req.Overlap)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid overlap: %v", err)})
+			return
+		}
+		overlap = d
+	}
+
+	id := c.Param("id")
+	cred, err := h.service.RotateWebhookCredential(c.Request.Context(), id, overlap)
+	if err != nil {
+		h.credentialError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"webhook_key": cred,
+		"replaces":    id,
+		"overlap":     overlap.String(),
+	})
+}
+
+// RevokeCredential disables a key immediately
+func (h *CredentialHandler) RevokeCredential(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.service.RevokeWebhookCredential(c.Request.Context(), id); err != nil {
+		h.credentialError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook key revoked", "id": id})
+}
+
+func (h *CredentialHandler) credentialError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidCredential):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCredentialNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
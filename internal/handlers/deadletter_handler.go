@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"licenseplate-plugin/internal/events"
+	"licenseplate-plugin/internal/services"
+	"licenseplate-plugin/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeadLetterHandler exposes inbound messages that failed to dispatch so
+// operators can inspect, retry or discard them
+type DeadLetterHandler struct {
+	service  *services.LicensePlateService
+	registry *events.Registry
+}
+
+func NewDeadLetterHandler(service *services.LicensePlateService, registry *events.Registry) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		service:  service,
+		registry: registry,
+	}
+}
+
+// ListDeadLetters lists failed messages filtered by channel, event type and handler
+func (h *DeadLetterHandler) ListDeadLetters(c *gin.Context) {
+	filter := storage.DeadLetterFilter{
+		Channel:   c.Query("channel"),
+		EventType: c.Query("event_type"),
+		Handler:   c.Query("handler"),
+		Limit:     defaultOutboxPageSize,
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxOutboxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		filter.Limit = limit
+	}
+	if v := c.Query("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+			return
+		}
+		filter.Offset = offset
+	}
+
+	letters, err := h.service.ListDeadLetters(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dead_letters": letters,
+		"count":        len(letters),
+		"limit":        filter.Limit,
+		"offset":       filter.Offset,
+	})
+}
+
+// GetDeadLetter returns one failed message with its payload and last error
+func (h *DeadLetterHandler) GetDeadLetter(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	dl, err := h.service.GetDeadLetter(c.Request.Context(), id)
+	if err != nil {
+		adminError(c, err, http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, dl)
+}
+
+// RetryDeadLetter dispatches a failed message again, to the handler that
+// failed if there was one. On success the dead letter is removed; on
+// failure its error and attempt count are updated.
+func (h *DeadLetterHandler) RetryDeadLetter(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	dl, err := h.service.GetDeadLetter(ctx, id)
+	if err != nil {
+		adminError(c, err, http.StatusNotFound)
+		return
+	}
+
+	if err := h.registry.Redispatch(ctx, dl); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "id": id})
+		return
+	}
+	if err := h.service.DeleteDeadLetter(ctx, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dead letter retried successfully", "id": id})
+}
+
+// DiscardDeadLetter removes a failed message without retrying it
+func (h *DeadLetterHandler) DiscardDeadLetter(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteDeadLetter(c.Request.Context(), id); err != nil {
+		adminError(c, err, http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dead letter discarded", "id": id})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"licenseplate-plugin/internal/events"
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/services"
+	"licenseplate-plugin/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// brokenDeadLetterStore fails dead letter lookups like an unreachable database
+type brokenDeadLetterStore struct {
+	*storage.MemoryStore
+}
+
+func (brokenDeadLetterStore) GetDeadLetter(ctx context.Context, id int64) (*models.DeadLetter, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (brokenDeadLetterStore) DeleteDeadLetter(ctx context.Context, id int64) error {
+	return errors.New("connection refused")
+}
+
+func TestDeadLetterAdminErrorStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name  string
+		store storage.Store
+		want  int
+	}{
+		{"missing dead letter", storage.NewMemoryStore(), http.StatusNotFound},
+		{"database failure", brokenDeadLetterStore{storage.NewMemoryStore()}, http.StatusInternalServerError},
+	}
+	requests := []struct{ method, path string }{
+		{http.MethodGet, "/dead-letters/42"},
+		{http.MethodPost, "/dead-letters/42/retry"},
+		{http.MethodDelete, "/dead-letters/42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewDeadLetterHandler(services.NewLicensePlateService(tt.store), events.NewRegistry())
+			router := gin.New()
+			router.GET("/dead-letters/:id", h.GetDeadLetter)
+			router.POST("/dead-letters/:id/retry", h.RetryDeadLetter)
+			router.DELETE("/dead-letters/:id", h.DiscardDeadLetter)
+
+			for _, r := range requests {
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, httptest.NewRequest(r.method, r.path, nil))
+				if rec.Code != tt.want {
+					t.Errorf("%s %s: status = %d, want %d: %s", r.method, r.path, rec.Code, tt.want, rec.Body)
+				}
+			}
+		})
+	}
+}
@@ -2,35 +2,66 @@ package handlers
 
 import (
     "context"
-    "encoding/json"
     "fmt"
     "log"
+    "net/http"
 
+    "licenseplate-plugin/internal/events"
     "licenseplate-plugin/internal/models"
     "licenseplate-plugin/internal/services"
+
+    "github.com/gin-gonic/gin"
 )
 
+// scannedHandlerName names HandleLicenseplateScanned in logs, metrics and
+// the processed-events ledger.
+const scannedHandlerName = "HandleLicenseplateScanned"
+
+// RegisterEventHandlers wires the plugin's bus event handlers into r.
+func RegisterEventHandlers(r *events.Registry, service *services.LicensePlateService) {
+    r.Register(models.EventLicensePlateScanned, scannedHandlerName, HandleLicenseplateScanned(service))
+}
+
 // HandleLicenseplateScanned is a typed handler for licenseplate.scanned events.
-// It unmarshals the payload and delegates to the service layer.
-func HandleLicenseplateScanned(service *services.LicensePlateService, ctx context.Context, raw json.RawMessage) error {
-    var payload models.XPOTSWebhookPayload
-    log.Printf("[handlers] HandleLicenseplateScanned: received raw payload: %s", string(raw))
-    if err := json.Unmarshal(raw, &payload); err != nil {
-        return fmt.Errorf("invalid licenseplate.scanned payload: %w", err)
-    }
-
-    // Minimal validation
-    if payload.PlateNumber == "" {
-        return fmt.Errorf("missing plate number in payload")
-    }
-
-    log.Printf("[handlers] HandleLicenseplateScanned: processing plate=%s", payload.PlateNumber)
-
-    // Delegate to existing service logic that already handles XPOTS payloads
-    if err := service.ProcessXPOTSWebhook(&payload); err != nil {
-        return fmt.Errorf("service.ProcessXPOTSWebhook failed: %w", err)
-    }
-
-    log.Printf("[handlers] HandleLicenseplateScanned: processed plate=%s successfully", payload.PlateNumber)
-    return nil
+// It delegates the payload to the service layer as an XPOTS detection.
+// Redelivered events are recognised by their id and acknowledged without
+// being processed again.
+func HandleLicenseplateScanned(service *services.LicensePlateService) events.Handler {
+    return events.Typed(func(ctx context.Context, ev *models.EventEnvelope, payload models.XPOTSWebhookPayload) error {
+        // Minimal validation
+        if payload.PlateNumber == "" {
+            return fmt.Errorf("missing plate number in payload")
+        }
+
+        log.Printf("[handlers] HandleLicenseplateScanned: processing plate=%s", payload.PlateNumber)
+
+        // Delegate to existing service logic that already handles XPOTS payloads
+        // Legacy events carry no id; the service then derives one from the payload
+        in := services.Ingest{Consumer: scannedHandlerName, EventID: ev.ID}
+        if _, err := service.ProcessDetection(ctx, in, payload.Detection()); err != nil {
+            return fmt.Errorf("service.ProcessDetection failed: %w", err)
+        }
+        return nil
+    })
+}
+
+// EventsHandler exposes the event dispatcher's handler metrics and queue.
+type EventsHandler struct {
+    metrics *events.Metrics
+    pool    *events.Pool
+}
+
+func NewEventsHandler(metrics *events.Metrics, pool *events.Pool) *EventsHandler {
+    return &EventsHandler{metrics: metrics, pool: pool}
+}
+
+// GetStats returns invocation counts, failures and panics per handler, and
+// the worker pool's queue depth and overflow counters
+func (h *EventsHandler) GetStats(c *gin.Context) {
+    stats := h.metrics.Snapshot()
+    c.JSON(http.StatusOK, gin.H{
+        "handlers": stats,
+        "count":    len(stats),
+        "pool":     h.pool.Stats(),
+    })
 }
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "licenseplate-plugin/internal/eventbus"
+    "licenseplate-plugin/internal/events"
+    "licenseplate-plugin/internal/models"
+    "licenseplate-plugin/internal/outbox"
+    "licenseplate-plugin/internal/services"
+    "licenseplate-plugin/internal/storage"
+)
+
+// TestOwnScannedEventsAreNotReprocessed is a regression test for the
+// feedback loop where licenseplate.scanned events published by the plugin
+// came back over the bus and were logged as XPOTS entries.
+func TestOwnScannedEventsAreNotReprocessed(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    svc := services.NewLicensePlateService(storage.NewMemoryStore())
+    svc.SetEventSource(services.DefaultEventSource + "/replica-a")
+
+    registry := events.NewRegistry()
+    registry.SkipOwnEvents(services.DefaultEventSource)
+    RegisterEventHandlers(registry, svc)
+
+    bus := eventbus.NewMemoryBus()
+    defer bus.Close()
+    dispatched := make(chan error, 2)
+    err := bus.Subscribe(ctx, services.EventsChannel, func(ctx context.Context, channel, message string) error {
+        err := registry.Dispatch(ctx, channel, message)
+        dispatched <- err
+        return err
+    })
+    if err != nil {
+        t.Fatalf("subscribe: %v", err)
+    }
+
+    // Our own scan goes out through the outbox like in production
+    if _, err := svc.ScanAndStore(ctx, models.ScanRequest{PlateNumber: "OWN123", GuestName: "Guest"}); err != nil {
+        t.Fatalf("scan: %v", err)
+    }
+    publisher := outbox.NewPublisher(svc, bus, outbox.Config{
+        Owner:     "test",
+        BatchSize: 10,
+        Lease:     time.Minute,
+        Retry:     outbox.DefaultRetryPolicy(),
+    })
+    if n, err := publisher.PublishBatch(ctx); err != nil || n != 1 {
+        t.Fatalf("publish batch = %d, %v; want 1 event", n, err)
+    }
+
+    // A scan from another producer must still be handled
+    foreign := `{"specversion":"1.0","id":"ext-1","source":"/gate-controller","type":"licenseplate.scanned",` +
+        `"data":{"event_type":"entry","plate_number":"EXT456","location":"Gate A"}}`
+    if err := bus.Publish(ctx, services.EventsChannel, foreign); err != nil {
+        t.Fatalf("publish foreign event: %v", err)
+    }
+
+    for i := 0; i < 2; i++ {
+        select {
+        case err := <-dispatched:
+            if err != nil {
+                t.Fatalf("dispatch: %v", err)
+            }
+        case <-time.After(5 * time.Second):
+            t.Fatal("timed out waiting for dispatch")
+        }
+    }
+
+    own, err := svc.GetParkingEvents(ctx, "OWN123")
+    if err != nil {
+        t.Fatalf("get parking events: %v", err)
+    }
+    if len(own) != 0 {
+        t.Fatalf("own scan was re-processed into %d parking events", len(own))
+    }
+    ext, err := svc.GetParkingEvents(ctx, "EXT456")
+    if err != nil {
+        t.Fatalf("get parking events: %v", err)
+    }
+    if len(ext) != 1 {
+        t.Fatalf("foreign scan logged %d parking events, want 1", len(ext))
+    }
+}
+
+func TestAcceptOwnEventsOptsIn(t *testing.T) {
+    registry := events.NewRegistry()
+    registry.SkipOwnEvents(services.DefaultEventSource)
+
+    var skipped, accepted int
+    registry.Register("test.event", "skipped", func(ctx context.Context, ev *models.EventEnvelope) error {
+        skipped++
+        return nil
+    })
+    registry.Register("test.event", "accepted", func(ctx context.Context, ev *models.EventEnvelope) error {
+        accepted++
+        return nil
+    }, events.AcceptOwnEvents())
+
+    own := `{"specversion":"1.0","id":"1","source":"/licenseplate-plugin/replica-b","type":"test.event","data":{}}`
+    if err := registry.Dispatch(context.Background(), services.EventsChannel, own); err != nil {
+        t.Fatalf("dispatch: %v", err)
+    }
+    if skipped != 0 || accepted != 1 {
+        t.Fatalf("skipped handler ran %d times, opted-in handler %d times; want 0 and 1", skipped, accepted)
+    }
+}
+
+func TestRedeliveredScannedEventIsProcessedOnce(t *testing.T) {
+    ctx := context.Background()
+    svc := services.NewLicensePlateService(storage.NewMemoryStore())
+    registry := events.NewRegistry()
+    RegisterEventHandlers(registry, svc)
+
+    ev := `{"specversion":"1.0","id":"ext-1","source":"/gate-controller","type":"licenseplate.scanned",` +
+        `"data":{"event_type":"entry","plate_number":"DUP123","location":"Gate A"}}`
+    for i := 0; i < 3; i++ {
+        if err := registry.Dispatch(ctx, services.EventsChannel, ev); err != nil {
+            t.Fatalf("dispatch %d: %v", i, err)
+        }
+    }
+
+    logged, err := svc.GetParkingEvents(ctx, "DUP123")
+    if err != nil {
+        t.Fatalf("get parking events: %v", err)
+    }
+    if len(logged) != 1 {
+        t.Fatalf("redelivered event logged %d parking events, want 1", len(logged))
+    }
+}
+
+func TestFailedEventsAreDeadLettered(t *testing.T) {
+    ctx := context.Background()
+    svc := services.NewLicensePlateService(storage.NewMemoryStore())
+    registry := events.NewRegistry()
+    RegisterEventHandlers(registry, svc)
+    registry.SetDeadLetterStore(svc)
+
+    // A producer using a different field name for the plate
+    ev := `{"specversion":"1.0","id":"ext-2","source":"/other-camera","type":"licenseplate.scanned","data":{"plate":"ABC123"}}`
+    for i := 0; i < 2; i++ {
+        if err := registry.Dispatch(ctx, services.EventsChannel, ev); err != nil {
+            t.Fatalf("dispatch %d: %v", i, err)
+        }
+    }
+    if err := registry.Dispatch(ctx, services.EventsChannel, `{not json`); err != nil {
+        t.Fatalf("dispatch invalid JSON: %v", err)
+    }
+
+    letters, err := svc.ListDeadLetters(ctx, storage.DeadLetterFilter{Handler: scannedHandlerName})
+    if err != nil {
+        t.Fatalf("list dead letters: %v", err)
+    }
+    if len(letters) != 1 || letters[0].EventID != "ext-2" || letters[0].Attempts != 2 {
+        t.Fatalf("handler dead letters = %+v, want one for ext-2 with 2 attempts", letters)
+    }
+
+    if err := registry.Redispatch(ctx, &letters[0]); err == nil {
+        t.Fatal("redispatch of a still invalid payload succeeded")
+    }
+    dl, err := svc.GetDeadLetter(ctx, letters[0].ID)
+    if err != nil {
+        t.Fatalf("get dead letter: %v", err)
+    }
+    if dl.Attempts != 3 {
+        t.Fatalf("attempts after retry = %d, want 3", dl.Attempts)
+    }
+
+    all, err := svc.ListDeadLetters(ctx, storage.DeadLetterFilter{})
+    if err != nil {
+        t.Fatalf("list dead letters: %v", err)
+    }
+    if len(all) != 2 {
+        t.Fatalf("got %d dead letters, want 2 (handler error and invalid JSON)", len(all))
+    }
+}
@@ -1,25 +1,20 @@
 package handlers
 
 import (
-	"encoding/json"
-	"licenseplate-plugin/internal/eventbus"
 	"licenseplate-plugin/internal/models"
 	"licenseplate-plugin/internal/services"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
 )
 
 type LicensePlateHandler struct {
-	service     *services.LicensePlateService
-	redisClient *redis.Client
+	service *services.LicensePlateService
 }
 
-func NewLicensePlateHandler(service *services.LicensePlateService, redisClient *redis.Client) *LicensePlateHandler {
+func NewLicensePlateHandler(service *services.LicensePlateService) *LicensePlateHandler {
 	return &LicensePlateHandler{
-		service:     service,
-		redisClient: redisClient,
+		service: service,
 	}
 }
 
@@ -30,27 +25,12 @@ func (h *LicensePlateHandler) ScanLicensePlate(c *gin.Context) {
 		return
 	}
 
-	record, err := h.service.ScanAndStore(req)
+	record, err := h.service.ScanAndStore(c.Request.Context(), req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Publish event to event bus asynchronously
-	go func(rec interface{}) {
-		payload := map[string]interface{}{
-			"type":   "licenseplate.scanned",
-			"record": rec,
-		}
-		if b, err := json.Marshal(payload); err == nil {
-			// write to outbox for reliable delivery
-			if _, err := h.service.InsertOutboxEvent("events", string(b)); err != nil {
-				// fallback: attempt direct publish if outbox write fails
-				_ = eventbus.Publish(c.Request.Context(), h.redisClient, "events", string(b))
-			}
-		}
-	}(record)
-
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "License plate scanned successfully",
 		"record":  record,
@@ -66,7 +46,7 @@ func (h *LicensePlateHandler) GetAllRecords(c *gin.Context) {
 		DateTo:      c.Query("date_to"),      // Filter to date
 	}
 
-	records := h.service.GetAllRecords(filters)
+	records := h.service.GetAllRecords(c.Request.Context(), filters)
 	c.JSON(http.StatusOK, gin.H{
 		"records": records,
 		"count":   len(records),
@@ -75,7 +55,7 @@ func (h *LicensePlateHandler) GetAllRecords(c *gin.Context) {
 
 func (h *LicensePlateHandler) GetRecord(c *gin.Context) {
 	plate := c.Param("plate")
-	record, err := h.service.GetRecord(plate)
+	record, err := h.service.GetRecord(c.Request.Context(), plate)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -86,7 +66,7 @@ func (h *LicensePlateHandler) GetRecord(c *gin.Context) {
 
 func (h *LicensePlateHandler) DeleteRecord(c *gin.Context) {
 	plate := c.Param("plate")
-	if err := h.service.DeleteRecord(plate); err != nil {
+	if err := h.service.DeleteRecord(c.Request.Context(), plate); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
@@ -97,7 +77,7 @@ func (h *LicensePlateHandler) DeleteRecord(c *gin.Context) {
 // GetParkingEvents retrieves the event history for a specific license plate
 func (h *LicensePlateHandler) GetParkingEvents(c *gin.Context) {
 	plate := c.Param("plate")
-	events, err := h.service.GetParkingEvents(plate)
+	events, err := h.service.GetParkingEvents(c.Request.Context(), plate)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve parking events"})
 		return
@@ -109,3 +89,76 @@ func (h *LicensePlateHandler) GetParkingEvents(c *gin.Context) {
 		"count":        len(events),
 	})
 }
+
+// GetStays returns a plate's visits, paired from its entries and exits by
+// the time the camera detected them
+func (h *LicensePlateHandler) GetStays(c *gin.Context) {
+	plate := c.Param("plate")
+	stays, err := h.service.GetStays(c.Request.Context(), plate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve stays"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"plate_number": plate,
+		"stays":        stays,
+		"count":        len(stays),
+	})
+}
+
+// GetOccupancy returns the vehicles currently parked
+func (h *LicensePlateHandler) GetOccupancy(c *gin.Context) {
+	occupancy, err := h.service.GetOccupancy(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve occupancy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, occupancy)
+}
+
+// AddToWatchlist adds a plate to the watchlist, or updates its reason
+func (h *LicensePlateHandler) AddToWatchlist(c *gin.Context) {
+	var req models.WatchlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := h.service.AddToWatchlist(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Plate added to watchlist",
+		"entry":   entry,
+	})
+}
+
+// GetWatchlist lists all watchlisted plates
+func (h *LicensePlateHandler) GetWatchlist(c *gin.Context) {
+	entries, err := h.service.GetWatchlist(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// RemoveFromWatchlist removes a plate from the watchlist
+func (h *LicensePlateHandler) RemoveFromWatchlist(c *gin.Context) {
+	plate := c.Param("plate")
+	if err := h.service.RemoveFromWatchlist(c.Request.Context(), plate); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Plate removed from watchlist"})
+}
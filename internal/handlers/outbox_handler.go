@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/services"
+	"licenseplate-plugin/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultOutboxPageSize = 50
+	maxOutboxPageSize     = 500
+)
+
+// OutboxHandler exposes the outbox_events queue to operators
+type OutboxHandler struct {
+	service *services.LicensePlateService
+}
+
+func NewOutboxHandler(service *services.LicensePlateService) *OutboxHandler {
+	return &OutboxHandler{
+		service: service,
+	}
+}
+
+// ListEvents lists outbox events filtered by status, channel and creation time
+func (h *OutboxHandler) ListEvents(c *gin.Context) {
+	filter := storage.OutboxFilter{
+		Status:  c.Query("status"),  // pending, sent, failed, discarded
+		Channel: c.Query("channel"), // Filter by bus channel
+		Limit:   defaultOutboxPageSize,
+	}
+
+	switch filter.Status {
+	case "", models.OutboxStatusPending, models.OutboxStatusSent, models.OutboxStatusFailed, models.OutboxStatusDiscarded:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of pending, sent, failed, discarded"})
+		return
+	}
+
+	var err error
+	if filter.CreatedAfter, err = parseTimeQuery(c, "created_after"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.CreatedBefore, err = parseTimeQuery(c, "created_before"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxOutboxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		filter.Limit = limit
+	}
+	if v := c.Query("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+			return
+		}
+		filter.Offset = offset
+	}
+
+	events, err := h.service.ListOutboxEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"count":  len(events),
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+// GetEvent returns a single outbox event including its last error
+func (h *OutboxHandler) GetEvent(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	event, err := h.service.GetOutboxEvent(c.Request.Context(), id)
+	if err != nil {
+		adminError(c, err, http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// RetryEvent schedules an unsent event for immediate redelivery
+func (h *OutboxHandler) RetryEvent(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.RequeueOutboxEvent(c.Request.Context(), id); err != nil {
+		adminError(c, err, http.StatusConflict)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Outbox event requeued", "id": id})
+}
+
+// CancelEvent stops delivery of a pending or failed event
+func (h *OutboxHandler) CancelEvent(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DiscardOutboxEvent(c.Request.Context(), id); err != nil {
+		adminError(c, err, http.StatusConflict)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Outbox event cancelled", "id": id})
+}
+
+// GetStats reports queue depth and the age of the oldest pending event
+func (h *OutboxHandler) GetStats(c *gin.Context) {
+	stats, err := h.service.GetOutboxStats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// adminError responds with notFoundStatus when the row is missing or not
+// in a state that allows the operation, and with 500 otherwise.
+func adminError(c *gin.Context, err error, notFoundStatus int) {
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(notFoundStatus, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// parseIDParam reads the numeric :id path parameter, writing a 400 response
+// and returning false when it is invalid.
+func parseIDParam(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// parseTimeQuery parses an optional RFC 3339 query parameter.
+func parseTimeQuery(c *gin.Context, key string) (time.Time, error) {
+	v := c.Query(key)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s format, use ISO 8601", key)
+	}
+	return t, nil
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"licenseplate-plugin/internal/services"
+	"licenseplate-plugin/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// brokenOutboxStore fails outbox admin operations like an unreachable database
+type brokenOutboxStore struct {
+	*storage.MemoryStore
+}
+
+func (brokenOutboxStore) RequeueOutboxEvent(ctx context.Context, id int64) error {
+	return errors.New("connection refused")
+}
+
+func TestOutboxAdminErrorStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name  string
+		store storage.Store
+		want  int
+	}{
+		{"missing event", storage.NewMemoryStore(), http.StatusConflict},
+		{"database failure", brokenOutboxStore{storage.NewMemoryStore()}, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/outbox/:id/retry", NewOutboxHandler(services.NewLicensePlateService(tt.store)).RetryEvent)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/outbox/42/retry", nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReplayHandler lets operators re-emit historical events for consumers
+// that need to rebuild their state
+type ReplayHandler struct {
+	service *services.LicensePlateService
+}
+
+func NewReplayHandler(service *services.LicensePlateService) *ReplayHandler {
+	return &ReplayHandler{
+		service: service,
+	}
+}
+
+// Replay enqueues historical events matching the request body to the
+// requested channel and reports how many were enqueued per type
+func (h *ReplayHandler) Replay(c *gin.Context) {
+	var req models.ReplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.ReplayEvents(c.Request.Context(), req)
+	if errors.Is(err, services.ErrInvalidReplay) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		// Batches enqueued before the failure stay enqueued
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, result)
+}
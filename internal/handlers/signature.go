@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// KeyIDHeader names the webhook credential the request is signed with.
+	KeyIDHeader = "X-Webhook-Key-Id"
+	// SignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of
+	// the timestamp, a dot and the raw request body.
+	SignatureHeader = "X-Webhook-Signature"
+	// TimestampHeader carries the time the request was signed, in Unix seconds.
+	TimestampHeader = "X-Webhook-Timestamp"
+
+	signaturePrefix = "sha256="
+
+	// maxWebhookBodySize bounds the body read for signature verification
+	maxWebhookBodySize = 1 << 20
+)
+
+var (
+	errMissingKeyID     = errors.New("missing key id header")
+	errUnknownKey       = errors.New("unknown, revoked or expired key")
+	errMissingSignature = errors.New("missing signature or timestamp header")
+	errBadTimestamp     = errors.New("invalid timestamp")
+	errStaleTimestamp   = errors.New("timestamp outside the tolerance window")
+	errBadSignature     = errors.New("invalid signature")
+	errReplayed         = errors.New("request already received")
+
+	authErrors = []error{errMissingKeyID, errUnknownKey, errMissingSignature, errBadTimestamp, errStaleTimestamp, errBadSignature, errReplayed}
+)
+
+// credentialContextKey stores the verified credential in the gin context.
+const credentialContextKey = "webhookCredential"
+
+// CredentialSource looks up active webhook credentials by key id.
+type CredentialSource interface {
+	WebhookCredential(ctx context.Context, id string) (*models.WebhookCredential, error)
+}
+
+// SignatureLedger records used signatures where every replica sees them.
+type SignatureLedger interface {
+	// ClaimWebhookSignature records signature for ttl and reports false if
+	// it was already recorded.
+	ClaimWebhookSignature(ctx context.Context, signature string, ttl time.Duration) (bool, error)
+}
+
+// SignatureVerifier authenticates webhook requests signed with the secret
+// of the credential named in KeyIDHeader, or with a fallback secret for
+// requests that name none. Each signature is accepted once: signatures seen
+// within the tolerance window are remembered, and older ones are rejected
+// by their timestamp, so a captured request cannot be replayed.
+//
+// Without a ledger (see SetLedger) signatures are remembered in memory,
+// which only protects a single instance: a request replayed to another
+// replica, or after a restart, is accepted again.
+type SignatureVerifier struct {
+	credentials CredentialSource
+	fallback    []byte // Unscoped secret for requests without a key id; nil disables them
+	tolerance   time.Duration
+	now         func() time.Time
+	ledger      SignatureLedger
+
+	mu   sync.Mutex
+	seen map[string]time.Time // Signature -> when it may be forgotten
+}
+
+func NewSignatureVerifier(credentials CredentialSource, fallbackSecret string, tolerance time.Duration) *SignatureVerifier {
+	v := &SignatureVerifier{
+		credentials: credentials,
+		tolerance:   tolerance,
+		now:         time.Now,
+		seen:        make(map[string]time.Time),
+	}
+	if fallbackSecret != "" {
+		v.fallback = []byte(fallbackSecret)
+	}
+	return v
+}
+
+// SetLedger makes v record used signatures in ledger instead of in memory,
+// so replicas sharing it reject each other's replays. Call it before v is
+// used.
+func (v *SignatureVerifier) SetLedger(ledger SignatureLedger) {
+	v.ledger = ledger
+}
+
+// Sign returns the signature header value for body signed at timestamp.
+func Sign(secret, timestamp string, body []byte) string {
+	return signaturePrefix + hex.EncodeToString(signatureMAC([]byte(secret), timestamp, body))
+}
+
+func signatureMAC(secret []byte, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// Verify checks the signature of body with the credential keyID, records
+// the signature as used and returns the credential. Requests with the
+// fallback secret get a credential without an ID or scope.
+func (v *SignatureVerifier) Verify(ctx context.Context, keyID, timestamp, signature string, body []byte) (*models.WebhookCredential, error) {
+	cred, err := v.credential(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.verify(ctx, []byte(cred.Secret), timestamp, signature, body); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+func (v *SignatureVerifier) credential(ctx context.Context, keyID string) (*models.WebhookCredential, error) {
+	if keyID == "" {
+		if v.fallback == nil {
+			return nil, errMissingKeyID
+		}
+		return &models.WebhookCredential{Secret: string(v.fallback), Active: true}, nil
+	}
+	cred, err := v.credentials.WebhookCredential(ctx, keyID)
+	if errors.Is(err, services.ErrCredentialNotFound) {
+		return nil, errUnknownKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("look up webhook key %s: %w", keyID, err)
+	}
+	return cred, nil
+}
+
+func (v *SignatureVerifier) verify(ctx context.Context, secret []byte, timestamp, signature string, body []byte) error {
+	if timestamp == "" || signature == "" {
+		return errMissingSignature
+	}
+	secs, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errBadTimestamp
+	}
+	now := v.now()
+	signedAt := time.Unix(secs, 0)
+	if signedAt.Before(now.Add(-v.tolerance)) || signedAt.After(now.Add(v.tolerance)) {
+		return errStaleTimestamp
+	}
+
+	got, ok := strings.CutPrefix(signature, signaturePrefix)
+	if !ok {
+		return errBadSignature
+	}
+	gotMAC, err := hex.DecodeString(got)
+	if err != nil {
+		return errBadSignature
+	}
+	if !hmac.Equal(gotMAC, signatureMAC(secret, timestamp, body)) {
+		return errBadSignature
+	}
+
+	if v.ledger != nil {
+		return v.claim(ctx, hex.EncodeToString(gotMAC))
+	}
+	return v.remember(hex.EncodeToString(gotMAC), signedAt.Add(v.tolerance), now)
+}
+
+// claim records a verified signature in the ledger and rejects one that was
+// already recorded. It is kept for twice the tolerance, so replicas whose
+// clocks disagree still reject the timestamp before forgetting it.
+func (v *SignatureVerifier) claim(ctx context.Context, signature string) error {
+	isNew, err := v.ledger.ClaimWebhookSignature(ctx, signature, 2*v.tolerance)
+	if err != nil {
+		return fmt.Errorf("record webhook signature: %w", err)
+	}
+	if !isNew {
+		return errReplayed
+	}
+	return nil
+}
+
+// remember records a verified signature until forgetAt, when its timestamp
+// leaves the tolerance window, and rejects one that was already recorded.
+func (v *SignatureVerifier) remember(signature string, forgetAt, now time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for sig, t := range v.seen {
+		if !t.After(now) {
+			delete(v.seen, sig)
+		}
+	}
+	if _, ok := v.seen[signature]; ok {
+		return errReplayed
+	}
+	v.seen[signature] = forgetAt
+	return nil
+}
+
+// RequireSignature rejects requests without a valid signature from v. The
+// body is read for verification and restored for the next handler, and the
+// credential is available through VerifiedCredential.
+func RequireSignature(v *SignatureVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodySize))
+		if err != nil {
+			status := http.StatusBadRequest
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			c.AbortWithStatusJSON(status, models.WebhookResponse{
+				Success: false,
+				Message: fmt.Sprintf("Failed to read request body: %v", err),
+			})
+			return
+		}
+
+		cred, err := v.Verify(c.Request.Context(), c.GetHeader(KeyIDHeader), c.GetHeader(TimestampHeader), c.GetHeader(SignatureHeader), body)
+		if err != nil {
+			status := http.StatusUnauthorized
+			message := fmt.Sprintf("Webhook authentication failed: %v", err)
+			if !isAuthError(err) {
+				log.Printf("Webhook authentication error: %v", err)
+				status, message = http.StatusInternalServerError, "Webhook authentication unavailable"
+			}
+			c.AbortWithStatusJSON(status, models.WebhookResponse{
+				Success: false,
+				Message: message,
+			})
+			return
+		}
+
+		c.Set(credentialContextKey, cred)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+// VerifiedCredential returns the credential RequireSignature accepted for
+// the request, or nil.
+func VerifiedCredential(c *gin.Context) *models.WebhookCredential {
+	cred, _ := c.Value(credentialContextKey).(*models.WebhookCredential)
+	return cred
+}
+
+func isAuthError(err error) bool {
+	for _, authErr := range authErrors {
+		if errors.Is(err, authErr) {
+			return true
+		}
+	}
+	return false
+}
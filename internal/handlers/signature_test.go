@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/services"
+	"licenseplate-plugin/internal/storage"
+)
+
+func TestSignatureVerifier(t *testing.T) {
+	const secret = "test-secret"
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"plate_number":"ABC123"}`)
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	newVerifier := func() *SignatureVerifier {
+		v := NewSignatureVerifier(nil, secret, 5*time.Minute)
+		v.now = func() time.Time { return now }
+		return v
+	}
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		body      []byte
+		want      error
+	}{
+		{"valid", ts, Sign(secret, ts, body), body, nil},
+		{"missing headers", "", "", body, errMissingSignature},
+		{"wrong secret", ts, Sign("other", ts, body), body, errBadSignature},
+		{"tampered body", ts, Sign(secret, ts, body), []byte(`{"plate_number":"XYZ999"}`), errBadSignature},
+		{"no prefix", ts, Sign(secret, ts, body)[len(signaturePrefix):], body, errBadSignature},
+		{"bad timestamp", "yesterday", Sign(secret, "yesterday", body), body, errBadTimestamp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newVerifier().Verify(context.Background(), "", tt.timestamp, tt.signature, tt.body); err != tt.want {
+				t.Fatalf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("stale timestamp", func(t *testing.T) {
+		old := strconv.FormatInt(now.Add(-6*time.Minute).Unix(), 10)
+		if _, err := newVerifier().Verify(context.Background(), "", old, Sign(secret, old, body), body); err != errStaleTimestamp {
+			t.Fatalf("Verify() = %v, want %v", err, errStaleTimestamp)
+		}
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		v := newVerifier()
+		sig := Sign(secret, ts, body)
+		if _, err := v.Verify(context.Background(), "", ts, sig, body); err != nil {
+			t.Fatalf("first Verify() = %v", err)
+		}
+		if _, err := v.Verify(context.Background(), "", ts, sig, body); err != errReplayed {
+			t.Fatalf("replayed Verify() = %v, want %v", err, errReplayed)
+		}
+
+		// After the window the nonce may be forgotten; the timestamp check
+		// still rejects the request
+		now = now.Add(6 * time.Minute)
+		if _, err := v.Verify(context.Background(), "", ts, sig, body); err != errStaleTimestamp {
+			t.Fatalf("late Verify() = %v, want %v", err, errStaleTimestamp)
+		}
+	})
+}
+
+func TestSignatureVerifierKeys(t *testing.T) {
+	ctx := context.Background()
+	svc := services.NewLicensePlateService(storage.NewMemoryStore())
+	v := NewSignatureVerifier(svc, "", 5*time.Minute)
+	body := []byte(`{"plate_number":"ABC123","camera_id":"CAM-1"}`)
+
+	// Each request needs its own timestamp, or the second would be a replay
+	now := time.Now()
+	verify := func(cred *models.WebhookCredential) (*models.WebhookCredential, error) {
+		now = now.Add(time.Second)
+		ts := strconv.FormatInt(now.Unix(), 10)
+		v.now = func() time.Time { return now }
+		return v.Verify(ctx, cred.ID, ts, Sign(cred.Secret, ts, body), body)
+	}
+
+	old, err := svc.IssueWebhookCredential(ctx, models.WebhookCredentialRequest{Site: "hq", CameraID: "CAM-1"})
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if got, err := verify(old); err != nil || got.ID != old.ID {
+		t.Fatalf("Verify() = %v, %v; want key %s", got, err, old.ID)
+	}
+
+	// Without a fallback secret every request must name a key
+	ts := strconv.FormatInt(now.Unix(), 10)
+	if _, err := v.Verify(ctx, "", ts, Sign(old.Secret, ts, body), body); err != errMissingKeyID {
+		t.Fatalf("Verify() without key id = %v, want %v", err, errMissingKeyID)
+	}
+
+	// Both keys work during the overlap
+	rotated, err := svc.RotateWebhookCredential(ctx, old.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	if rotated.CameraID != "CAM-1" || rotated.Site != "hq" {
+		t.Fatalf("rotated key scope = %q/%q, want hq/CAM-1", rotated.Site, rotated.CameraID)
+	}
+	for _, cred := range []*models.WebhookCredential{old, rotated} {
+		if _, err := verify(cred); err != nil {
+			t.Fatalf("Verify() with %s during overlap = %v", cred.ID, err)
+		}
+	}
+
+	// A key signed with another key's secret is rejected
+	if _, err := verify(&models.WebhookCredential{ID: rotated.ID, Secret: "guess"}); err != errBadSignature {
+		t.Fatalf("Verify() with wrong secret = %v, want %v", err, errBadSignature)
+	}
+
+	if err := svc.RevokeWebhookCredential(ctx, old.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := verify(old); err != errUnknownKey {
+		t.Fatalf("Verify() with revoked key = %v, want %v", err, errUnknownKey)
+	}
+	if _, err := verify(&models.WebhookCredential{ID: "whk_missing", Secret: "x"}); err != errUnknownKey {
+		t.Fatalf("Verify() with unknown key = %v, want %v", err, errUnknownKey)
+	}
+}
+
+// TestSignatureVerifierSharedLedger checks that verifiers sharing a ledger,
+// as replicas sharing a database do, reject each other's replays.
+func TestSignatureVerifierSharedLedger(t *testing.T) {
+	const secret = "test-secret"
+	ctx := context.Background()
+	svc := services.NewLicensePlateService(storage.NewMemoryStore())
+	body := []byte(`{"plate_number":"ABC123"}`)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	sig := Sign(secret, ts, body)
+
+	replicas := make([]*SignatureVerifier, 2)
+	for i := range replicas {
+		replicas[i] = NewSignatureVerifier(nil, secret, 5*time.Minute)
+		replicas[i].SetLedger(svc)
+	}
+
+	if _, err := replicas[0].Verify(ctx, "", ts, sig, body); err != nil {
+		t.Fatalf("Verify() on first replica = %v", err)
+	}
+	if _, err := replicas[1].Verify(ctx, "", ts, sig, body); err != errReplayed {
+		t.Fatalf("Verify() replayed to second replica = %v, want %v", err, errReplayed)
+	}
+
+	// A different request is still accepted by either replica
+	other := []byte(`{"plate_number":"XYZ999"}`)
+	if _, err := replicas[1].Verify(ctx, "", ts, Sign(secret, ts, other), other); err != nil {
+		t.Fatalf("Verify() of a new request = %v", err)
+	}
+}
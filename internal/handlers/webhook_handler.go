@@ -1,63 +1,67 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"os"
-	"strings"
 
+	"licenseplate-plugin/internal/ingest"
 	"licenseplate-plugin/internal/models"
 	"licenseplate-plugin/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
+// webhookConsumerPrefix plus the vendor identifies a webhook in the
+// processed-events ledger, e.g. "webhook/xpots".
+const webhookConsumerPrefix = "webhook/"
+
+// maxBatchSize bounds the number of detections in one batch request
+const maxBatchSize = 1000
+
 type WebhookHandler struct {
-	service *services.LicensePlateService
-	apiKey  string
+	service     *services.LicensePlateService
+	adapters    *ingest.Registry
+	cameraSites ingest.CameraSites
 }
 
-func NewWebhookHandler(service *services.LicensePlateService) *WebhookHandler {
-	apiKey := os.Getenv("WEBHOOK_API_KEY")
-	if apiKey == "" {
-		log.Println("WARNING: WEBHOOK_API_KEY not set - webhook endpoint will be insecure!")
-		apiKey = "default-insecure-key"
-	}
-	
+// NewWebhookHandler creates the camera webhook handler, which decodes
+// payloads with the adapter for the vendor in the route. Requests must be
+// authenticated before they reach it (see RequireSignature).
+func NewWebhookHandler(service *services.LicensePlateService, adapters *ingest.Registry) *WebhookHandler {
 	return &WebhookHandler{
-		service: service,
-		apiKey:  apiKey,
+		service:  service,
+		adapters: adapters,
 	}
 }
 
-// HandleXPOTSWebhook receives license plate data from XPOTS system
-func (h *WebhookHandler) HandleXPOTSWebhook(c *gin.Context) {
-	// Validate API key from header
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, models.WebhookResponse{
-			Success: false,
-			Message: "Missing Authorization header",
-		})
-		return
-	}
+// SetCameraSites sets the site of each camera, which keys scoped to a site
+// are checked against. Keys scoped to a site reject cameras not listed, so
+// every camera that reports with a site key must be mapped. Call it before
+// the handler is used.
+func (h *WebhookHandler) SetCameraSites(sites ingest.CameraSites) {
+	h.cameraSites = sites
+}
 
-	// Support both "Bearer TOKEN" and "TOKEN" formats
-	token := strings.TrimPrefix(authHeader, "Bearer ")
-	if token != h.apiKey {
-		c.JSON(http.StatusUnauthorized, models.WebhookResponse{
-			Success: false,
-			Message: "Invalid API key",
-		})
+// HandleWebhook receives license plate data from the camera vendor named
+// by the :vendor route parameter
+func (h *WebhookHandler) HandleWebhook(c *gin.Context) {
+	vendor, adapter, body, ok := h.readVendorRequest(c)
+	if !ok {
 		return
 	}
 
-	// Parse XPOTS payload
-	var payload models.XPOTSWebhookPayload
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		log.Printf("Failed to parse XPOTS webhook payload: %v", err)
-		c.JSON(http.StatusBadRequest, models.WebhookResponse{
+	// Parse the vendor payload
+	payload, err := adapter.Decode(c.GetHeader("Content-Type"), body)
+	if err != nil {
+		log.Printf("Failed to parse %s webhook payload: %v", vendor, err)
+		status := http.StatusBadRequest
+		if errors.Is(err, ingest.ErrUnsupportedContentType) {
+			status = http.StatusUnsupportedMediaType
+		}
+		c.JSON(status, models.WebhookResponse{
 			Success: false,
 			Message: fmt.Sprintf("Invalid payload format: %v", err),
 		})
@@ -65,13 +69,32 @@ func (h *WebhookHandler) HandleXPOTSWebhook(c *gin.Context) {
 	}
 
 	// Log the incoming webhook
-	log.Printf("Received XPOTS webhook - Event: %s, Plate: %s, Time: %s, Location: %s",
-		payload.EventType, payload.PlateNumber, payload.Timestamp, payload.Location)
+	log.Printf("Received %s webhook - Event: %s, Plate: %s, Time: %s, Location: %s",
+		vendor, payload.EventType, payload.PlateNumber, payload.Timestamp, payload.Location)
 
-	// Process the webhook through service layer
-	err := h.service.ProcessXPOTSWebhook(&payload)
+	// A key scoped to a site or camera may only report for it
+	site := h.cameraSites.Site(payload.CameraID)
+	if site != "" {
+		payload.Site = site
+	}
+	in := services.Ingest{Consumer: webhookConsumerPrefix + vendor}
+	if cred := VerifiedCredential(c); cred != nil {
+		if !cred.Allows(site, payload.CameraID) {
+			c.JSON(http.StatusForbidden, models.WebhookResponse{
+				Success: false,
+				Message: fmt.Sprintf("Key %s is not valid for camera %q at site %q", cred.ID, payload.CameraID, site),
+				Plate:   payload.PlateNumber,
+			})
+			return
+		}
+		in.KeyID = cred.ID
+	}
+
+	// Process the webhook through service layer; cameras send no event id,
+	// so retried deliveries are recognised by an id derived from the payload
+	processed, err := h.service.ProcessDetection(c.Request.Context(), in, payload)
 	if err != nil {
-		log.Printf("Error processing XPOTS webhook: %v", err)
+		log.Printf("Error processing %s webhook: %v", vendor, err)
 		c.JSON(http.StatusInternalServerError, models.WebhookResponse{
 			Success: false,
 			Message: fmt.Sprintf("Failed to process webhook: %v", err),
@@ -80,7 +103,17 @@ func (h *WebhookHandler) HandleXPOTSWebhook(c *gin.Context) {
 		return
 	}
 
-	// Send success response back to XPOTS
+	// A duplicate still succeeds so the camera stops retrying it
+	if !processed {
+		c.JSON(http.StatusOK, models.WebhookResponse{
+			Success: true,
+			Message: fmt.Sprintf("Duplicate %s event for plate %s already processed", payload.EventType, payload.PlateNumber),
+			Plate:   payload.PlateNumber,
+		})
+		return
+	}
+
+	// Send success response back to the camera
 	c.JSON(http.StatusOK, models.WebhookResponse{
 		Success: true,
 		Message: fmt.Sprintf("Successfully processed %s event for plate %s", payload.EventType, payload.PlateNumber),
@@ -88,14 +121,133 @@ func (h *WebhookHandler) HandleXPOTSWebhook(c *gin.Context) {
 	})
 }
 
+// HandleBatchWebhook receives an array of detections from the camera
+// vendor named by the :vendor route parameter, such as a buffer flushed
+// after a network outage. Detections are processed in timestamp order and
+// reported one by one; invalid, rejected or failed items and duplicates do
+// not fail the rest of the batch.
+func (h *WebhookHandler) HandleBatchWebhook(c *gin.Context) {
+	vendor, adapter, body, ok := h.readVendorRequest(c)
+	if !ok {
+		return
+	}
+
+	items, err := ingest.DecodeBatch(adapter, c.GetHeader("Content-Type"), body)
+	if err != nil {
+		log.Printf("Failed to parse %s webhook batch: %v", vendor, err)
+		status := http.StatusBadRequest
+		if errors.Is(err, ingest.ErrUnsupportedContentType) {
+			status = http.StatusUnsupportedMediaType
+		}
+		c.JSON(status, models.WebhookResponse{
+			Success: false,
+			Message: fmt.Sprintf("Invalid batch format: %v", err),
+		})
+		return
+	}
+	if len(items) > maxBatchSize {
+		c.JSON(http.StatusRequestEntityTooLarge, models.WebhookResponse{
+			Success: false,
+			Message: fmt.Sprintf("Batch of %d detections exceeds the limit of %d", len(items), maxBatchSize),
+		})
+		return
+	}
+
+	log.Printf("Received %s webhook batch of %d detections", vendor, len(items))
+
+	// Items that cannot be decoded, or come from a site or camera outside
+	// the key's scope, fail on their own and are left out of processing
+	in := services.Ingest{Consumer: webhookConsumerPrefix + vendor}
+	cred := VerifiedCredential(c)
+	if cred != nil {
+		in.KeyID = cred.ID
+	}
+	results := make([]models.BatchItemResult, len(items))
+	detections := make([]*models.Detection, len(items))
+	for i, item := range items {
+		results[i].Index = i
+		if item.Err != nil {
+			results[i].Error = fmt.Sprintf("invalid payload format: %v", item.Err)
+			continue
+		}
+		d := item.Detection
+		site := h.cameraSites.Site(d.CameraID)
+		if site != "" {
+			d.Site = site
+		}
+		results[i].Plate = d.PlateNumber
+		if cred != nil && !cred.Allows(site, d.CameraID) {
+			results[i].Error = fmt.Sprintf("key %s is not valid for camera %q at site %q", cred.ID, d.CameraID, site)
+			continue
+		}
+		detections[i] = d
+	}
+
+	// Batches are retried as a whole, and items processed before are
+	// reported as duplicates
+	resp := models.BatchWebhookResponse{Results: results}
+	for i, r := range h.service.ProcessDetectionBatch(c.Request.Context(), in, detections) {
+		switch {
+		case detections[i] == nil:
+			results[i].Status = models.BatchItemFailed
+		case r.Err != nil:
+			log.Printf("Error processing %s webhook batch item %d: %v", vendor, i, r.Err)
+			results[i].Status = models.BatchItemFailed
+			results[i].Error = fmt.Sprintf("failed to process detection: %v", r.Err)
+		case r.Processed:
+			results[i].Status = models.BatchItemProcessed
+			resp.Processed++
+			continue
+		default:
+			results[i].Status = models.BatchItemDuplicate
+			resp.Duplicates++
+			continue
+		}
+		resp.Failed++
+	}
+
+	resp.Success = resp.Failed == 0
+	resp.Message = fmt.Sprintf("Processed %d of %d detections (%d duplicates, %d failed)",
+		resp.Processed, len(items), resp.Duplicates, resp.Failed)
+	c.JSON(http.StatusOK, resp)
+}
+
+// readVendorRequest resolves the adapter for the :vendor route parameter
+// and reads the request body. On failure it writes the response and
+// reports false.
+func (h *WebhookHandler) readVendorRequest(c *gin.Context) (string, ingest.Adapter, []byte, bool) {
+	vendor := c.Param("vendor")
+	adapter, ok := h.adapters.Lookup(vendor)
+	if !ok {
+		c.JSON(http.StatusNotFound, models.WebhookResponse{
+			Success: false,
+			Message: fmt.Sprintf("No webhook adapter for vendor %q", vendor),
+		})
+		return "", nil, nil, false
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.WebhookResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to read request body: %v", err),
+		})
+		return "", nil, nil, false
+	}
+	return vendor, adapter, body, true
+}
+
 // GetWebhookInfo provides information about the webhook endpoint
 func (h *WebhookHandler) GetWebhookInfo(c *gin.Context) {
 	info := gin.H{
-		"endpoint": "/api/licenseplate/webhook/xpots",
-		"method":   "POST",
+		"endpoint":       "/api/licenseplate/webhook/:vendor",
+		"batch_endpoint": "/api/licenseplate/webhook/:vendor/batch",
+		"method":         "POST",
+		"vendors":        h.adapters.Vendors(),
 		"authentication": gin.H{
-			"type":   "API Key",
-			"header": "Authorization: Bearer YOUR_API_KEY",
+			"type":      "HMAC-SHA256",
+			"headers":   []string{KeyIDHeader + ": <key id>", TimestampHeader + ": <unix seconds>", SignatureHeader + ": sha256=<hex>"},
+			"signature": "hex HMAC-SHA256 of <timestamp>.<raw body> keyed with the secret of the key",
 		},
 		"payload_example": models.XPOTSWebhookPayload{
 			EventType:   "entry",
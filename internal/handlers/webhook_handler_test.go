@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"licenseplate-plugin/internal/ingest"
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/services"
+	"licenseplate-plugin/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBatchWebhookReportsEachItem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	svc := services.NewLicensePlateService(storage.NewMemoryStore())
+	cred, err := svc.IssueWebhookCredential(ctx, models.WebhookCredentialRequest{CameraID: "CAM-1"})
+	if err != nil {
+		t.Fatalf("issue key: %v", err)
+	}
+
+	adapters := ingest.NewRegistry()
+	if err := adapters.Register(ingest.XPOTSAdapter{}); err != nil {
+		t.Fatalf("register adapter: %v", err)
+	}
+	router := gin.New()
+	verifier := NewSignatureVerifier(svc, "", 5*time.Minute)
+	router.POST("/webhook/:vendor/batch", RequireSignature(verifier), NewWebhookHandler(svc, adapters).HandleBatchWebhook)
+
+	// Each request is signed at a new second, or a retry would be a replay
+	signedAt := time.Now()
+	post := func(body string) models.BatchWebhookResponse {
+		t.Helper()
+		signedAt = signedAt.Add(time.Second)
+		ts := strconv.FormatInt(signedAt.Unix(), 10)
+		req := httptest.NewRequest(http.MethodPost, "/webhook/xpots/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(KeyIDHeader, cred.ID)
+		req.Header.Set(TimestampHeader, ts)
+		req.Header.Set(SignatureHeader, Sign(cred.Secret, ts, []byte(body)))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		var resp models.BatchWebhookResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return resp
+	}
+
+	// The exit is sent before the entry it ends
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	at := func(d time.Duration) string { return base.Add(d).Format(time.RFC3339) }
+	body := `[
+		{"event_type":"exit","plate_number":"BUF1","camera_id":"CAM-1","timestamp":"` + at(30*time.Minute) + `"},
+		{"event_type":"entry","plate_number":"BUF1","camera_id":"CAM-1","timestamp":"` + at(0) + `"},
+		{"event_type":"entry","plate_number":"BUF2","camera_id":"CAM-2","timestamp":"` + at(time.Minute) + `"},
+		{"event_type":"entry","plate_number":42}
+	]`
+	resp := post(body)
+	want := []string{models.BatchItemProcessed, models.BatchItemProcessed, models.BatchItemFailed, models.BatchItemFailed}
+	for i, r := range resp.Results {
+		if r.Index != i || r.Status != want[i] {
+			t.Fatalf("result %d = %+v, want status %s", i, r, want[i])
+		}
+	}
+	if resp.Success || resp.Processed != 2 || resp.Failed != 2 {
+		t.Fatalf("response = %+v, want 2 processed and 2 failed", resp)
+	}
+
+	// Processed in timestamp order, so neither event is out of order
+	stays, err := svc.GetStays(ctx, "BUF1")
+	if err != nil {
+		t.Fatalf("stays: %v", err)
+	}
+	if len(stays) != 1 || stays[0].DurationSeconds != 30*60 || stays[0].Entry.OutOfOrder || stays[0].Exit.OutOfOrder {
+		t.Fatalf("stays = %+v, want one ordered 30 minute stay", stays)
+	}
+
+	// A retried batch reports the items processed before as duplicates
+	resp = post(body)
+	if resp.Duplicates != 2 || resp.Processed != 0 || resp.Results[0].Status != models.BatchItemDuplicate {
+		t.Fatalf("retried batch = %+v, want 2 duplicates", resp)
+	}
+}
+
+func TestWebhookEnforcesKeySite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	svc := services.NewLicensePlateService(storage.NewMemoryStore())
+	cred, err := svc.IssueWebhookCredential(ctx, models.WebhookCredentialRequest{Site: "hq"})
+	if err != nil {
+		t.Fatalf("issue key: %v", err)
+	}
+
+	adapters := ingest.NewRegistry()
+	if err := adapters.Register(ingest.XPOTSAdapter{}); err != nil {
+		t.Fatalf("register adapter: %v", err)
+	}
+	handler := NewWebhookHandler(svc, adapters)
+	handler.SetCameraSites(ingest.CameraSites{"CAM-HQ": "hq", "CAM-DEPOT": "depot"})
+	verifier := NewSignatureVerifier(svc, "", 5*time.Minute)
+	router := gin.New()
+	router.POST("/webhook/:vendor", RequireSignature(verifier), handler.HandleWebhook)
+	router.POST("/webhook/:vendor/batch", RequireSignature(verifier), handler.HandleBatchWebhook)
+
+	signedAt := time.Now()
+	post := func(path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		signedAt = signedAt.Add(time.Second)
+		ts := strconv.FormatInt(signedAt.Unix(), 10)
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(KeyIDHeader, cred.ID)
+		req.Header.Set(TimestampHeader, ts)
+		req.Header.Set(SignatureHeader, Sign(cred.Secret, ts, []byte(body)))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The payload's site is signed by the key under test, so it never
+	// vouches for a camera
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"camera at the key's site", `{"event_type":"entry","plate_number":"SITE1","camera_id":"CAM-HQ"}`, http.StatusOK},
+		{"foreign camera", `{"event_type":"entry","plate_number":"SITE2","camera_id":"CAM-DEPOT"}`, http.StatusForbidden},
+		{"foreign camera claiming the key's site", `{"event_type":"entry","plate_number":"SITE3","camera_id":"CAM-DEPOT","site":"hq"}`, http.StatusForbidden},
+		{"unmapped camera claiming the key's site", `{"event_type":"entry","plate_number":"SITE4","camera_id":"CAM-NEW","site":"hq"}`, http.StatusForbidden},
+		{"unmapped camera without a site", `{"event_type":"entry","plate_number":"SITE5","camera_id":"CAM-NEW"}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if rec := post("/webhook/xpots", tt.body); rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+
+	rec := post("/webhook/xpots/batch", `[
+		{"event_type":"entry","plate_number":"BATCH1","camera_id":"CAM-HQ"},
+		{"event_type":"entry","plate_number":"BATCH2","camera_id":"CAM-NEW","site":"hq"},
+		{"event_type":"entry","plate_number":"BATCH3","camera_id":"CAM-DEPOT","site":"hq"}
+	]`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("batch status = %d: %s", rec.Code, rec.Body)
+	}
+	var resp models.BatchWebhookResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode batch response: %v", err)
+	}
+	want := []string{models.BatchItemProcessed, models.BatchItemFailed, models.BatchItemFailed}
+	for i, r := range resp.Results {
+		if r.Status != want[i] {
+			t.Errorf("batch item %d = %+v, want status %s", i, r, want[i])
+		}
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("batch results = %+v, want %d", resp.Results, len(want))
+	}
+}
@@ -0,0 +1,84 @@
+// Package ingest decodes camera webhooks from different ANPR vendors into
+// models.Detection. Each vendor is served by an Adapter registered under its
+// name, which is also the last segment of its webhook route.
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+
+	"licenseplate-plugin/internal/models"
+)
+
+// ErrUnsupportedContentType is returned by adapters for payload formats
+// they cannot decode.
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// Adapter decodes one vendor's webhook payload.
+type Adapter interface {
+	// Vendor names the adapter; it must be a single lowercase path segment.
+	Vendor() string
+	// Decode turns a request body of the given content type into a
+	// detection. Errors describe what is wrong with the payload.
+	Decode(contentType string, body []byte) (*models.Detection, error)
+}
+
+// Registry maps vendor names to adapters.
+type Registry struct {
+	adapters map[string]Adapter
+}
+
+func NewRegistry() *Registry {
+	return &Registry{adapters: make(map[string]Adapter)}
+}
+
+// Register adds a. It fails if the vendor name is invalid or taken.
+func (r *Registry) Register(a Adapter) error {
+	vendor := a.Vendor()
+	if vendor == "" || vendor != strings.ToLower(vendor) || strings.ContainsAny(vendor, "/ ") {
+		return fmt.Errorf("invalid vendor name %q: must be a lowercase path segment", vendor)
+	}
+	if _, ok := r.adapters[vendor]; ok {
+		return fmt.Errorf("adapter for vendor %q already registered", vendor)
+	}
+	r.adapters[vendor] = a
+	return nil
+}
+
+// Lookup returns the adapter for vendor.
+func (r *Registry) Lookup(vendor string) (Adapter, bool) {
+	a, ok := r.adapters[vendor]
+	return a, ok
+}
+
+// Vendors lists the registered vendor names in order.
+func (r *Registry) Vendors() []string {
+	vendors := make([]string, 0, len(r.adapters))
+	for v := range r.adapters {
+		vendors = append(vendors, v)
+	}
+	sort.Strings(vendors)
+	return vendors
+}
+
+// payloadFormat classifies a Content-Type header as "json" or "xml". A
+// missing content type is treated as JSON.
+func payloadFormat(contentType string) (string, error) {
+	if contentType == "" {
+		return "json", nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+	}
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return "json", nil
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return "xml", nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnsupportedContentType, mediaType)
+}
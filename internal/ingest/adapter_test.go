@@ -0,0 +1,124 @@
+package ingest
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestXPOTSAdapterDecodesJSONAndXML(t *testing.T) {
+	bodies := map[string]string{
+		"application/json": `{"event_type":"entry","plate_number":"ABC123","timestamp":"2024-05-01T08:30:00Z","camera_id":"CAM-1","confidence":0.97}`,
+		"application/xml": `<detection><event_type>entry</event_type><plate_number>ABC123</plate_number>` +
+			`<timestamp>2024-05-01T08:30:00Z</timestamp><camera_id>CAM-1</camera_id><confidence>0.97</confidence></detection>`,
+	}
+	for contentType, body := range bodies {
+		d, err := XPOTSAdapter{}.Decode(contentType, []byte(body))
+		if err != nil {
+			t.Fatalf("%s: Decode() = %v", contentType, err)
+		}
+		want := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+		if d.Vendor != "xpots" || d.PlateNumber != "ABC123" || d.CameraID != "CAM-1" || d.Confidence != 0.97 || !d.Timestamp.Equal(want) {
+			t.Fatalf("%s: Decode() = %+v", contentType, d)
+		}
+	}
+
+	if _, err := (XPOTSAdapter{}).Decode("text/plain", []byte("ABC123")); !errors.Is(err, ErrUnsupportedContentType) {
+		t.Fatalf("Decode(text/plain) = %v, want %v", err, ErrUnsupportedContentType)
+	}
+}
+
+func TestFieldMappingAdapter(t *testing.T) {
+	adapter, err := NewFieldMappingAdapter(FieldMapping{
+		Vendor: "acme",
+		Fields: map[string]string{
+			FieldPlateNumber: "result.plates.0.text",
+			FieldEventType:   "event",
+			FieldTimestamp:   "captured_ms",
+			FieldConfidence:  "result.plates.0.score",
+			FieldCameraID:    "device.serial",
+			FieldLaneNumber:  "device.lane",
+			FieldLocation:    "device.site_name",
+		},
+		EventTypes:      map[string]string{"ARRIVE": "entry", "LEAVE": "exit"},
+		TimeFormat:      "unix_ms",
+		ConfidenceScale: 100,
+	})
+	if err != nil {
+		t.Fatalf("NewFieldMappingAdapter() = %v", err)
+	}
+
+	body := `{"event":"LEAVE","captured_ms":1714552200000,` +
+		`"device":{"serial":"AC-9","lane":"2"},` +
+		`"result":{"plates":[{"text":"XYZ 789","score":88.5}]}}`
+	d, err := adapter.Decode("application/json; charset=utf-8", []byte(body))
+	if err != nil {
+		t.Fatalf("Decode() = %v", err)
+	}
+	if d.Vendor != "acme" || d.PlateNumber != "XYZ 789" || d.EventType != "exit" || d.CameraID != "AC-9" ||
+		d.LaneNumber != 2 || d.Confidence != 0.885 || d.Location != "" {
+		t.Fatalf("Decode() = %+v", d)
+	}
+	if want := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC); !d.Timestamp.Equal(want) {
+		t.Fatalf("timestamp = %s, want %s", d.Timestamp, want)
+	}
+
+	if _, err := adapter.Decode("application/json", []byte(`{"result":{"plates":[]}}`)); err == nil {
+		t.Fatal("Decode() without a plate succeeded")
+	}
+	if _, err := adapter.Decode("application/json", []byte(`{"captured_ms":"soon","result":{"plates":[{"text":"A1"}]}}`)); err == nil {
+		t.Fatal("Decode() with an invalid timestamp succeeded")
+	}
+	if _, err := adapter.Decode("application/xml", []byte(`<plate>A1</plate>`)); !errors.Is(err, ErrUnsupportedContentType) {
+		t.Fatalf("Decode(xml) = %v, want %v", err, ErrUnsupportedContentType)
+	}
+}
+
+func TestRegistryRejectsInvalidAdapters(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(XPOTSAdapter{}); err != nil {
+		t.Fatalf("Register() = %v", err)
+	}
+	if err := r.Register(XPOTSAdapter{}); err == nil {
+		t.Fatal("duplicate Register() succeeded")
+	}
+	if _, err := NewFieldMappingAdapter(FieldMapping{Vendor: "acme", Fields: map[string]string{FieldCameraID: "camera"}}); err == nil {
+		t.Fatal("mapping without plate_number accepted")
+	}
+	if _, err := NewFieldMappingAdapter(FieldMapping{Vendor: "acme", Fields: map[string]string{FieldPlateNumber: "plate", "colour": "color"}}); err == nil {
+		t.Fatal("mapping with unknown field accepted")
+	}
+	bad, _ := NewFieldMappingAdapter(FieldMapping{Vendor: "Acme/Cams", Fields: map[string]string{FieldPlateNumber: "plate"}})
+	if err := r.Register(bad); err == nil {
+		t.Fatal("Register() accepted a vendor name that is not a path segment")
+	}
+}
+
+func TestDecodeBatch(t *testing.T) {
+	xmlBatch := `<detections>` +
+		`<detection><plate_number>ABC123</plate_number><camera_id>CAM-1</camera_id></detection>` +
+		`<detection><plate_number>XYZ789</plate_number><confidence>high</confidence></detection>` +
+		`</detections>`
+	items, err := DecodeBatch(XPOTSAdapter{}, "text/xml", []byte(xmlBatch))
+	if err != nil {
+		t.Fatalf("DecodeBatch(xml) = %v", err)
+	}
+	if len(items) != 2 || items[0].Err != nil || items[0].Detection.PlateNumber != "ABC123" || items[1].Err == nil {
+		t.Fatalf("DecodeBatch(xml) = %+v, want ABC123 and one invalid item", items)
+	}
+
+	mapping, err := NewFieldMappingAdapter(FieldMapping{Vendor: "acme", Fields: map[string]string{FieldPlateNumber: "plate"}})
+	if err != nil {
+		t.Fatalf("NewFieldMappingAdapter() = %v", err)
+	}
+	items, err = DecodeBatch(mapping, "application/json", []byte(`[{"plate":"A1"},{"plate":"B2"},{}]`))
+	if err != nil {
+		t.Fatalf("DecodeBatch(json) = %v", err)
+	}
+	if len(items) != 3 || items[1].Detection.PlateNumber != "B2" || items[2].Err == nil {
+		t.Fatalf("DecodeBatch(json) = %+v", items)
+	}
+	if _, err := DecodeBatch(mapping, "application/json", []byte(`{"plate":"A1"}`)); err == nil {
+		t.Fatal("DecodeBatch() accepted a single object")
+	}
+}
@@ -0,0 +1,65 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"licenseplate-plugin/internal/models"
+)
+
+// BatchSplitter is implemented by adapters that accept batches in a format
+// other than a JSON array. SplitBatch returns the body of each item, which
+// Decode accepts with the same content type.
+type BatchSplitter interface {
+	SplitBatch(contentType string, body []byte) ([][]byte, error)
+}
+
+// BatchItem is one decoded item of a batch. Err is set instead of Detection
+// when the item could not be decoded.
+type BatchItem struct {
+	Detection *models.Detection
+	Err       error
+}
+
+// DecodeBatch decodes a batch of detections with a. The body is a JSON
+// array of payloads unless a implements BatchSplitter. Items are decoded
+// one by one, so an invalid item is reported in its BatchItem without
+// failing the others; the error is for a body that is not a batch at all.
+func DecodeBatch(a Adapter, contentType string, body []byte) ([]BatchItem, error) {
+	var raw [][]byte
+	if splitter, ok := a.(BatchSplitter); ok {
+		var err error
+		if raw, err = splitter.SplitBatch(contentType, body); err != nil {
+			return nil, err
+		}
+	} else {
+		format, err := payloadFormat(contentType)
+		if err != nil {
+			return nil, err
+		}
+		if format != "json" {
+			return nil, fmt.Errorf("%w: %s batches must be JSON arrays", ErrUnsupportedContentType, a.Vendor())
+		}
+		if raw, err = splitJSONArray(body); err != nil {
+			return nil, err
+		}
+	}
+
+	items := make([]BatchItem, len(raw))
+	for i, item := range raw {
+		items[i].Detection, items[i].Err = a.Decode(contentType, item)
+	}
+	return items, nil
+}
+
+func splitJSONArray(body []byte) ([][]byte, error) {
+	var messages []json.RawMessage
+	if err := json.Unmarshal(body, &messages); err != nil {
+		return nil, fmt.Errorf("batch must be a JSON array: %w", err)
+	}
+	raw := make([][]byte, len(messages))
+	for i, m := range messages {
+		raw[i] = m
+	}
+	return raw, nil
+}
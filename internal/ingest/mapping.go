@@ -0,0 +1,218 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"licenseplate-plugin/internal/models"
+)
+
+// Detection fields a FieldMapping can map.
+const (
+	FieldPlateNumber = "plate_number"
+	FieldEventType   = "event_type"
+	FieldTimestamp   = "timestamp"
+	FieldLocation    = "location"
+	FieldConfidence  = "confidence"
+	FieldImageURL    = "image_url"
+	FieldCameraID    = "camera_id"
+	FieldSite        = "site"
+	FieldVehicleType = "vehicle_type"
+	FieldDirection   = "direction"
+	FieldLaneNumber  = "lane_number"
+)
+
+var mappableFields = map[string]bool{
+	FieldPlateNumber: true, FieldEventType: true, FieldTimestamp: true, FieldLocation: true, FieldConfidence: true,
+	FieldImageURL: true, FieldCameraID: true, FieldSite: true, FieldVehicleType: true, FieldDirection: true, FieldLaneNumber: true,
+}
+
+// FieldMapping configures a FieldMappingAdapter for one vendor.
+type FieldMapping struct {
+	Vendor string `json:"vendor"`
+	// Fields maps detection fields to dot-separated paths in the payload,
+	// e.g. "plate_number": "result.plate.text". Numeric segments index
+	// arrays. plate_number is required.
+	Fields map[string]string `json:"fields"`
+	// EventTypes translates the vendor's event_type values, e.g.
+	// {"ARRIVE": "entry", "LEAVE": "exit"}. Unlisted values pass through.
+	EventTypes map[string]string `json:"event_types,omitempty"`
+	// TimeFormat is a Go time layout, or "unix" / "unix_ms" for epoch
+	// numbers. RFC 3339 by default.
+	TimeFormat string `json:"time_format,omitempty"`
+	// ConfidenceScale divides the confidence, e.g. 100 for percentages.
+	ConfidenceScale float64 `json:"confidence_scale,omitempty"`
+}
+
+// FieldMappingAdapter decodes JSON webhooks of any shape by picking
+// detection fields out of the payload according to a FieldMapping.
+type FieldMappingAdapter struct {
+	mapping FieldMapping
+}
+
+// NewFieldMappingAdapter validates m and returns an adapter for it.
+func NewFieldMappingAdapter(m FieldMapping) (*FieldMappingAdapter, error) {
+	if m.Vendor == "" {
+		return nil, errors.New("field mapping: vendor is required")
+	}
+	if m.Fields[FieldPlateNumber] == "" {
+		return nil, fmt.Errorf("field mapping %s: %s must be mapped", m.Vendor, FieldPlateNumber)
+	}
+	for field, path := range m.Fields {
+		if !mappableFields[field] {
+			return nil, fmt.Errorf("field mapping %s: unknown field %q", m.Vendor, field)
+		}
+		if path == "" {
+			return nil, fmt.Errorf("field mapping %s: empty path for %s", m.Vendor, field)
+		}
+	}
+	if m.ConfidenceScale < 0 {
+		return nil, fmt.Errorf("field mapping %s: confidence_scale must not be negative", m.Vendor)
+	}
+	return &FieldMappingAdapter{mapping: m}, nil
+}
+
+// LoadFieldMappings reads a JSON array of field mappings from path.
+func LoadFieldMappings(path string) ([]FieldMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var mappings []FieldMapping
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return mappings, nil
+}
+
+func (a *FieldMappingAdapter) Vendor() string { return a.mapping.Vendor }
+
+func (a *FieldMappingAdapter) Decode(contentType string, body []byte) (*models.Detection, error) {
+	format, err := payloadFormat(contentType)
+	if err != nil {
+		return nil, err
+	}
+	if format != "json" {
+		return nil, fmt.Errorf("%w: %s accepts JSON only", ErrUnsupportedContentType, a.mapping.Vendor)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var payload interface{}
+	if err := dec.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", a.mapping.Vendor, err)
+	}
+
+	d := &models.Detection{Vendor: a.mapping.Vendor}
+	for field, path := range a.mapping.Fields {
+		value, ok := lookupPath(payload, path)
+		if !ok || value == nil {
+			continue
+		}
+		if err := a.setField(d, field, value); err != nil {
+			return nil, fmt.Errorf("%s (%s): %w", field, path, err)
+		}
+	}
+	if d.PlateNumber == "" {
+		return nil, fmt.Errorf("%s missing at %s", FieldPlateNumber, a.mapping.Fields[FieldPlateNumber])
+	}
+	return d, nil
+}
+
+func (a *FieldMappingAdapter) setField(d *models.Detection, field string, value interface{}) error {
+	s, err := scalarString(value)
+	if err != nil {
+		return err
+	}
+
+	switch field {
+	case FieldPlateNumber:
+		d.PlateNumber = s
+	case FieldEventType:
+		if mapped, ok := a.mapping.EventTypes[s]; ok {
+			s = mapped
+		}
+		d.EventType = s
+	case FieldTimestamp:
+		d.Timestamp, err = parseTime(s, a.mapping.TimeFormat)
+	case FieldLocation:
+		d.Location = s
+	case FieldConfidence:
+		d.Confidence, err = strconv.ParseFloat(s, 64)
+		if a.mapping.ConfidenceScale > 0 {
+			d.Confidence /= a.mapping.ConfidenceScale
+		}
+	case FieldImageURL:
+		d.ImageURL = s
+	case FieldCameraID:
+		d.CameraID = s
+	case FieldSite:
+		d.Site = s
+	case FieldVehicleType:
+		d.VehicleType = s
+	case FieldDirection:
+		d.Direction = s
+	case FieldLaneNumber:
+		d.LaneNumber, err = strconv.Atoi(s)
+	}
+	return err
+}
+
+// lookupPath follows a dot-separated path through decoded JSON.
+func lookupPath(v interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			next, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// scalarString renders a JSON string, number or bool as a string.
+func scalarString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", errors.New("expected a string, number or boolean")
+}
+
+func parseTime(s, format string) (time.Time, error) {
+	switch format {
+	case "":
+		return time.Parse(time.RFC3339Nano, s)
+	case "unix", "unix_ms":
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if format == "unix_ms" {
+			return time.UnixMilli(int64(n)).UTC(), nil
+		}
+		return time.Unix(0, int64(n*float64(time.Second))).UTC(), nil
+	}
+	return time.Parse(format, s)
+}
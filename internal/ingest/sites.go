@@ -0,0 +1,38 @@
+package ingest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CameraSites maps camera ids to the site each camera is installed at.
+type CameraSites map[string]string
+
+// ParseCameraSites parses a comma-separated list of camera=site pairs,
+// e.g. "CAM-1=hq,CAM-2=depot".
+func ParseCameraSites(s string) (CameraSites, error) {
+	sites := make(CameraSites)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		camera, site, ok := strings.Cut(pair, "=")
+		camera, site = strings.TrimSpace(camera), strings.TrimSpace(site)
+		if !ok || camera == "" || site == "" {
+			return nil, fmt.Errorf("invalid camera site %q: want camera=site", pair)
+		}
+		if prev, dup := sites[camera]; dup && prev != site {
+			return nil, fmt.Errorf("camera %s mapped to both %s and %s", camera, prev, site)
+		}
+		sites[camera] = site
+	}
+	return sites, nil
+}
+
+// Site returns the site cameraID is mapped to, or "" for an unmapped
+// camera. The site stated in a payload is signed by the camera's key and
+// cannot vouch for the camera, so it is not consulted.
+func (m CameraSites) Site(cameraID string) string {
+	return m[cameraID]
+}
@@ -0,0 +1,58 @@
+package ingest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+
+	"licenseplate-plugin/internal/models"
+)
+
+// XPOTSAdapter decodes XPOTS webhooks, sent as JSON or as XML with the same
+// field names. XML batches wrap one element per detection in a root element,
+// e.g. <detections><detection>...</detection></detections>.
+type XPOTSAdapter struct{}
+
+func (XPOTSAdapter) Vendor() string { return "xpots" }
+
+func (XPOTSAdapter) Decode(contentType string, body []byte) (*models.Detection, error) {
+	format, err := payloadFormat(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload models.XPOTSWebhookPayload
+	if format == "xml" {
+		err = xml.Unmarshal(body, &payload)
+	} else {
+		err = json.Unmarshal(body, &payload)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid XPOTS payload: %w", err)
+	}
+	return payload.Detection(), nil
+}
+
+func (XPOTSAdapter) SplitBatch(contentType string, body []byte) ([][]byte, error) {
+	format, err := payloadFormat(contentType)
+	if err != nil {
+		return nil, err
+	}
+	if format == "json" {
+		return splitJSONArray(body)
+	}
+
+	var batch struct {
+		Items []struct {
+			Inner []byte `xml:",innerxml"`
+		} `xml:",any"`
+	}
+	if err := xml.Unmarshal(body, &batch); err != nil {
+		return nil, fmt.Errorf("invalid XPOTS batch: %w", err)
+	}
+	raw := make([][]byte, len(batch.Items))
+	for i, item := range batch.Items {
+		raw[i] = append(append([]byte("<detection>"), item.Inner...), "</detection>"...)
+	}
+	return raw, nil
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Detection is a plate read from a camera, independent of the vendor's
+// payload format. Ingest adapters decode vendor webhooks into it.
+type Detection struct {
+	Vendor      string    `json:"vendor"`       // Adapter that decoded it, e.g. "xpots"
+	EventType   string    `json:"event_type"`   // "entry" or "exit"; vendor values such as "in" or "scan" are accepted
+	PlateNumber string    `json:"plate_number"` // License plate detected
+	Timestamp   time.Time `json:"timestamp"`    // When the camera read the plate
+	Location    string    `json:"location"`     // Camera/gate location
+	Confidence  float64   `json:"confidence"`   // Recognition confidence (0-1)
+	ImageURL    string    `json:"image_url,omitempty"`
+	CameraID    string    `json:"camera_id"`
+	Site        string    `json:"site,omitempty"` // Site the camera is installed at
+	VehicleType string    `json:"vehicle_type,omitempty"`
+	Direction   string    `json:"direction,omitempty"`
+	LaneNumber  int       `json:"lane_number,omitempty"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Event types published on the bus. The payload of each is documented on
+// the struct carried as the envelope's data.
+const (
+	EventLicensePlateScanned    = "licenseplate.scanned"     // LicensePlateRecord
+	EventLicensePlateUpdated    = "licenseplate.updated"     // LicensePlateUpdatedEvent
+	EventLicensePlateDeleted    = "licenseplate.deleted"     // LicensePlateDeletedEvent
+	EventVehicleEntered         = "vehicle.entered"          // VehicleEnteredEvent
+	EventVehicleExited          = "vehicle.exited"           // VehicleExitedEvent
+	EventVehicleUnknownDetected = "vehicle.unknown_detected" // VehicleUnknownDetectedEvent
+	EventWatchlistHit           = "watchlist.hit"            // WatchlistHitEvent
+	EventAccessExpired          = "access.expired"           // AccessExpiredEvent
+)
+
+// LicensePlateUpdatedEvent is published when a scan changes a plate that was
+// already registered. The record fields describe the plate after the
+// update; Previous holds it as it was before.
+type LicensePlateUpdatedEvent struct {
+	LicensePlateRecord
+	Previous LicensePlateRecord `json:"previous"`
+}
+
+// LicensePlateDeletedEvent is published when a registration is deleted. The
+// record fields describe the plate as it was before deletion.
+type LicensePlateDeletedEvent struct {
+	LicensePlateRecord
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// VehicleEnteredEvent is published when a camera detects a vehicle entering.
+// The parking event fields describe the logged entry.
+type VehicleEnteredEvent struct {
+	ParkingEvent
+	Known         bool   `json:"known"`                  // Plate was registered before this detection
+	GuestName     string `json:"guest_name,omitempty"`   // From the registration, if known
+	VisitorType   string `json:"visitor_type,omitempty"` // From the registration, if known
+	AccessExpired bool   `json:"access_expired"`         // The registration's access_expires_at had passed
+}
+
+// VehicleExitedEvent is published when a camera detects a vehicle leaving.
+// The parking event fields describe the logged exit.
+type VehicleExitedEvent struct {
+	ParkingEvent
+	Known       bool       `json:"known"`                  // Plate was registered before this detection
+	GuestName   string     `json:"guest_name,omitempty"`   // From the registration, if known
+	VisitorType string     `json:"visitor_type,omitempty"` // From the registration, if known
+	EntryTime   *time.Time `json:"entry_time,omitempty"`   // Event time of the entry this exit ends, if detected
+	StaySeconds int64      `json:"stay_seconds,omitempty"` // Time since EntryTime
+}
+
+// VehicleUnknownDetectedEvent is published when a camera detects a plate with
+// no registration. A placeholder visitor record is created for it, so the
+// event fires only on the first detection.
+type VehicleUnknownDetectedEvent struct {
+	ParkingEvent
+}
+
+// WatchlistHitEvent is published whenever a camera detects a plate on the
+// watchlist, in addition to vehicle.entered or vehicle.exited.
+type WatchlistHitEvent struct {
+	ParkingEvent
+	Reason        string    `json:"reason"`         // Why the plate is watchlisted
+	WatchlistedAt time.Time `json:"watchlisted_at"` // When it was added to the watchlist
+}
+
+// AccessExpiredEvent is published once when a registration's
+// access_expires_at passes. Setting a new expiry re-arms it.
+type AccessExpiredEvent struct {
+	PlateNumber     string    `json:"plate_number"`
+	GuestName       string    `json:"guest_name"`
+	RoomNumber      string    `json:"room_number,omitempty"`
+	VisitorType     string    `json:"visitor_type"`
+	AccessExpiresAt time.Time `json:"access_expires_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventSpecVersion is the CloudEvents specification version of EventEnvelope.
+const EventSpecVersion = "1.0"
+
+// EventSchemaVersion is the version of the data payloads carried in event
+// envelopes. Bump it when a payload changes incompatibly.
+const EventSchemaVersion = "1"
+
+// EventEnvelope is the CloudEvents 1.0 JSON envelope for events published on
+// the bus. SchemaVersion, CorrelationID and Replay are CloudEvents extension
+// attributes, which is why their JSON names are lowercase without separators.
+type EventEnvelope struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	SchemaVersion   string          `json:"schemaversion"`
+	CorrelationID   string          `json:"correlationid,omitempty"`
+	Replay          bool            `json:"replay,omitempty"` // Re-emitted from history, not live traffic
+	Data            json.RawMessage `json:"data"`
+}
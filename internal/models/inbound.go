@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// SpilledEvent is an inbound bus message parked in inbound_event_spill
+// because the dispatcher queue was full
+type SpilledEvent struct {
+	ID        int64     `json:"id"`
+	Channel   string    `json:"channel"`
+	Payload   string    `json:"payload"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DeadLetter is an inbound bus message that failed to dispatch, kept in
+// inbound_dead_letters for inspection and retry. Handler is empty when the
+// message never reached a handler (invalid JSON or no handler for its type).
+type DeadLetter struct {
+	ID            int64     `json:"id"`
+	Channel       string    `json:"channel"`
+	EventID       string    `json:"event_id"` // Derived from the payload when the message has none
+	EventType     string    `json:"event_type,omitempty"`
+	Handler       string    `json:"handler,omitempty"`
+	Payload       string    `json:"payload"`
+	Error         string    `json:"error"`
+	Attempts      int       `json:"attempts"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at"`
+}
@@ -2,12 +2,33 @@ package models
 
 import "time"
 
+// Outbox event statuses
+const (
+    OutboxStatusPending   = "pending"   // Waiting for (re)delivery
+    OutboxStatusSent      = "sent"      // Published to the event bus
+    OutboxStatusFailed    = "failed"    // Dead-lettered after too many attempts
+    OutboxStatusDiscarded = "discarded" // Dropped by an operator
+)
+
 type OutboxEvent struct {
-    ID        int64      `json:"id"`
-    Channel   string     `json:"channel"`
-    Payload   string     `json:"payload"`
-    Attempts  int        `json:"attempts"`
-    LastError string     `json:"last_error,omitempty"`
-    CreatedAt time.Time  `json:"created_at"`
-    SentAt    *time.Time `json:"sent_at,omitempty"`
+    ID            int64      `json:"id"`
+    Channel       string     `json:"channel"`
+    Payload       string     `json:"payload"`
+    Status        string     `json:"status"`
+    Attempts      int        `json:"attempts"`
+    LastError     string     `json:"last_error,omitempty"`
+    NextAttemptAt time.Time  `json:"next_attempt_at"`
+    CreatedAt     time.Time  `json:"created_at"`
+    SentAt        *time.Time `json:"sent_at,omitempty"`
+    LockedBy      string     `json:"locked_by,omitempty"`    // Publisher instance holding the lease
+    LockedUntil   *time.Time `json:"locked_until,omitempty"` // Lease expiry
+}
+
+// OutboxStats summarizes the outbox queue for monitoring
+type OutboxStats struct {
+    Counts                  map[string]int `json:"counts"`                               // Rows per status
+    Pending                 int            `json:"pending"`                              // Rows still waiting for delivery
+    Due                     int            `json:"due"`                                  // Pending rows whose next attempt is due now
+    OldestPendingAt         *time.Time     `json:"oldest_pending_at,omitempty"`          // created_at of the oldest pending row
+    OldestPendingAgeSeconds float64        `json:"oldest_pending_age_seconds,omitempty"` // Age of the oldest pending row
 }
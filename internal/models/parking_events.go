@@ -4,15 +4,37 @@ import "time"
 
 // ParkingEvent represents a single entry or exit event for a vehicle
 type ParkingEvent struct {
-	ID          int       `json:"id"`
-	PlateNumber string    `json:"plate_number"`
-	EventType   string    `json:"event_type"` // "entry" or "exit"
-	EventTime   time.Time `json:"event_time"`
-	Location    string    `json:"location,omitempty"`
-	CameraID    string    `json:"camera_id,omitempty"`
-	Confidence  float64   `json:"confidence,omitempty"`
-	Notes       string    `json:"notes,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID           int       `json:"id"`
+	PlateNumber  string    `json:"plate_number"`
+	EventType    string    `json:"event_type"`             // "entry" or "exit"
+	EventTime    time.Time `json:"event_time"`             // When the camera detected the vehicle
+	ReceivedAt   time.Time `json:"received_at"`            // When the plugin received the detection
+	ClockSkew    bool      `json:"clock_skew,omitempty"`   // EventTime and ReceivedAt differ by more than the skew threshold
+	OutOfOrder   bool      `json:"out_of_order,omitempty"` // A later detection of the plate arrived first
+	Location     string    `json:"location,omitempty"`
+	CameraID     string    `json:"camera_id,omitempty"`
+	Confidence   float64   `json:"confidence,omitempty"`
+	Notes        string    `json:"notes,omitempty"`
+	WebhookKeyID string    `json:"webhook_key_id,omitempty"` // Credential that authenticated the detection
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Stay is one visit of a vehicle, paired from its entry and exit events in
+// event time order. Exit is nil while the vehicle is still parked, and Entry
+// is nil for an exit whose entry was never detected.
+type Stay struct {
+	PlateNumber     string        `json:"plate_number"`
+	Entry           *ParkingEvent `json:"entry,omitempty"`
+	Exit            *ParkingEvent `json:"exit,omitempty"`
+	DurationSeconds int64         `json:"duration_seconds,omitempty"` // Set when both entry and exit are known
+}
+
+// Occupancy lists the vehicles whose latest detection, by event time, is an
+// entry.
+type Occupancy struct {
+	Count      int            `json:"count"`
+	ByLocation map[string]int `json:"by_location"` // Entry location -> vehicles
+	Vehicles   []ParkingEvent `json:"vehicles"`    // The entry event of each parked vehicle
 }
 
 // GuestReservation represents guest booking system data (placeholder for future integration)
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ReplayableEvents are the event types that can be rebuilt from history:
+// licenseplate.scanned from license_plates, vehicle.entered and
+// vehicle.exited from parking_events.
+var ReplayableEvents = []string{EventLicensePlateScanned, EventVehicleEntered, EventVehicleExited}
+
+// ReplayRequest selects historical events to re-emit through the outbox.
+// Zero fields are ignored.
+type ReplayRequest struct {
+	Channel     string    `json:"channel" binding:"required"` // Bus channel to publish to
+	From        time.Time `json:"from"`                       // Occurred at or after
+	To          time.Time `json:"to"`                         // Occurred before
+	PlateNumber string    `json:"plate_number"`
+	EventTypes  []string  `json:"event_types"` // Subset of ReplayableEvents; all if empty
+	Location    string    `json:"location"`    // Parking events only; excludes licenseplate.scanned
+}
+
+// ReplayResult reports how many events a replay enqueued.
+type ReplayResult struct {
+	Channel       string         `json:"channel"`
+	CorrelationID string         `json:"correlation_id"` // Shared by all events of the replay
+	Enqueued      map[string]int `json:"enqueued"`       // Per event type
+	Total         int            `json:"total"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// WatchlistEntry is a plate that raises watchlist.hit when detected
+type WatchlistEntry struct {
+	PlateNumber string    `json:"plate_number"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WatchlistRequest adds a plate to the watchlist
+type WatchlistRequest struct {
+	PlateNumber string `json:"plate_number" binding:"required"`
+	Reason      string `json:"reason" binding:"required"`
+}
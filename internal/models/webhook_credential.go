@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// WebhookCredential is a webhook signing key scoped to a site, a camera,
+// or a camera at a site. Requests name the key in the X-Webhook-Key-Id
+// header and are signed with its secret.
+type WebhookCredential struct {
+	ID          string     `json:"id"`
+	Site        string     `json:"site,omitempty"`
+	CameraID    string     `json:"camera_id,omitempty"` // Only detections from this camera are accepted
+	Secret      string     `json:"secret,omitempty"`    // Only returned when the key is issued
+	Description string     `json:"description,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"` // Set when rotated: end of the overlap window
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	RotatedTo   string     `json:"rotated_to,omitempty"` // Key that replaced this one
+	Active      bool       `json:"active"`               // Neither revoked nor expired
+}
+
+// Allows reports whether the credential may submit detections from cameraID
+// at site. A key scoped to a site rejects detections whose site is unknown.
+func (c *WebhookCredential) Allows(site, cameraID string) bool {
+	return (c.Site == "" || c.Site == site) && (c.CameraID == "" || c.CameraID == cameraID)
+}
+
+// WebhookCredentialRequest issues a new credential.
+type WebhookCredentialRequest struct {
+	Site        string `json:"site"`
+	CameraID    string `json:"camera_id"`
+	Description string `json:"description"`
+}
+
+// RotateCredentialRequest rotates a credential. Overlap is how long the old
+// key keeps working, as a Go duration such as "24h".
+type RotateCredentialRequest struct {
+	Overlap string `json:"overlap"`
+}
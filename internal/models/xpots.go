@@ -2,21 +2,40 @@ package models
 
 import "time"
 
-// XPOTSWebhookPayload represents the data structure sent by XPOTS
-// Adjust fields based on actual XPOTS API documentation
+// XPOTSWebhookPayload represents the data structure sent by XPOTS, as JSON
+// or XML. Adjust fields based on actual XPOTS API documentation
 type XPOTSWebhookPayload struct {
-	EventType   string    `json:"event_type"`   // e.g., "entry", "exit", "scan"
-	PlateNumber string    `json:"plate_number"` // License plate detected
-	Timestamp   time.Time `json:"timestamp"`    // When the plate was scanned
-	Location    string    `json:"location"`     // Camera/gate location
-	Confidence  float64   `json:"confidence"`   // Recognition confidence (0-1)
-	ImageURL    string    `json:"image_url"`    // URL to plate image (if available)
-	CameraID    string    `json:"camera_id"`    // ID of the camera that detected the plate
-	
+	EventType   string    `json:"event_type" xml:"event_type"`     // e.g., "entry", "exit", "scan"
+	PlateNumber string    `json:"plate_number" xml:"plate_number"` // License plate detected
+	Timestamp   time.Time `json:"timestamp" xml:"timestamp"`       // When the plate was scanned
+	Location    string    `json:"location" xml:"location"`         // Camera/gate location
+	Confidence  float64   `json:"confidence" xml:"confidence"`     // Recognition confidence (0-1)
+	ImageURL    string    `json:"image_url" xml:"image_url"`       // URL to plate image (if available)
+	CameraID    string    `json:"camera_id" xml:"camera_id"`       // ID of the camera that detected the plate
+	Site        string    `json:"site" xml:"site"`                 // Site the camera is installed at
+
 	// Additional fields that might be provided
-	VehicleType  string `json:"vehicle_type"`  // car, motorcycle, truck, etc.
-	Direction    string `json:"direction"`     // in, out
-	LaneNumber   int    `json:"lane_number"`   // Which lane/gate
+	VehicleType string `json:"vehicle_type" xml:"vehicle_type"` // car, motorcycle, truck, etc.
+	Direction   string `json:"direction" xml:"direction"`       // in, out
+	LaneNumber  int    `json:"lane_number" xml:"lane_number"`   // Which lane/gate
+}
+
+// Detection converts the payload to the vendor-neutral detection model.
+func (p *XPOTSWebhookPayload) Detection() *Detection {
+	return &Detection{
+		Vendor:      "xpots",
+		EventType:   p.EventType,
+		PlateNumber: p.PlateNumber,
+		Timestamp:   p.Timestamp,
+		Location:    p.Location,
+		Confidence:  p.Confidence,
+		ImageURL:    p.ImageURL,
+		CameraID:    p.CameraID,
+		Site:        p.Site,
+		VehicleType: p.VehicleType,
+		Direction:   p.Direction,
+		LaneNumber:  p.LaneNumber,
+	}
 }
 
 // WebhookResponse is sent back to XPOTS to acknowledge receipt
@@ -25,3 +44,29 @@ type WebhookResponse struct {
 	Message string `json:"message"`
 	Plate   string `json:"plate_number,omitempty"`
 }
+
+// Outcomes of an item of a batch webhook
+const (
+	BatchItemProcessed = "processed"
+	BatchItemDuplicate = "duplicate"
+	BatchItemFailed    = "failed"
+)
+
+// BatchWebhookResponse acknowledges a batch of detections. Items succeed or
+// fail on their own; Results has one entry per item, in request order.
+type BatchWebhookResponse struct {
+	Success    bool              `json:"success"` // No item failed
+	Message    string            `json:"message"`
+	Processed  int               `json:"processed"`
+	Duplicates int               `json:"duplicates"`
+	Failed     int               `json:"failed"`
+	Results    []BatchItemResult `json:"results"`
+}
+
+// BatchItemResult reports the outcome of one item of a batch.
+type BatchItemResult struct {
+	Index  int    `json:"index"`  // Position in the request
+	Status string `json:"status"` // BatchItemProcessed, BatchItemDuplicate or BatchItemFailed
+	Plate  string `json:"plate_number,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
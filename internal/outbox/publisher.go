@@ -0,0 +1,133 @@
+// Package outbox delivers events from the outbox_events table to the event bus.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"licenseplate-plugin/internal/eventbus"
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/services"
+	"licenseplate-plugin/internal/storage"
+)
+
+// Publisher claims batches of outbox events and publishes them. Rows are
+// leased to the publisher's owner id while it works on them, so any number
+// of replicas can run a Publisher against the same table and each row goes
+// out once per successful attempt.
+type Publisher struct {
+	svc    *services.LicensePlateService
+	bus    eventbus.Publisher
+	config Config
+}
+
+// Config controls how a Publisher claims and retries events.
+type Config struct {
+	Owner     string        // Instance id recorded on leased rows
+	BatchSize int           // Rows claimed per batch
+	Lease     time.Duration // How long claimed rows stay reserved
+	Retry     RetryPolicy
+}
+
+// NewPublisher creates a publisher. The lease must comfortably exceed the
+// time needed to publish a batch; rows whose lease expires may be claimed
+// by another publisher.
+func NewPublisher(svc *services.LicensePlateService, bus eventbus.Publisher, config Config) *Publisher {
+	return &Publisher{
+		svc:    svc,
+		bus:    bus,
+		config: config,
+	}
+}
+
+// Start runs the publisher in a background goroutine until ctx is
+// cancelled. It drains the outbox whenever wake fires (e.g. on a Postgres
+// NOTIFY) and every interval as a fallback. wake may be nil to poll only.
+func (p *Publisher) Start(ctx context.Context, interval time.Duration, wake <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Println("[OutboxPublisher] context canceled, stopping publisher")
+				return
+			case <-wake:
+				p.drain(ctx)
+			case <-ticker.C:
+				p.drain(ctx)
+			}
+		}
+	}()
+}
+
+// drain publishes batches until a claim comes back short, meaning nothing
+// else is due right now.
+func (p *Publisher) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		claimed, err := p.PublishBatch(ctx)
+		if err != nil {
+			log.Printf("[OutboxPublisher] claim error: %v", err)
+			return
+		}
+		if claimed < p.config.BatchSize {
+			return
+		}
+	}
+}
+
+// PublishBatch claims one batch of due events and publishes them. On
+// success each event is marked as sent; on failure the error is recorded
+// and the event rescheduled according to the retry policy. It returns the
+// number of claimed rows.
+func (p *Publisher) PublishBatch(ctx context.Context) (int, error) {
+	events, err := p.svc.ClaimOutboxEvents(ctx, p.config.Owner, p.config.BatchSize, p.config.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range events {
+		// attempt publish
+		if err := p.bus.Publish(ctx, e.Channel, e.Payload); err != nil {
+			log.Printf("[OutboxPublisher] publish failed id=%d: %v", e.ID, err)
+			p.retryLater(ctx, e, err.Error())
+			continue
+		}
+
+		// mark as sent; a lost lease means the row now belongs to another
+		// publisher or was changed by an admin, so it is left alone
+		err := p.svc.MarkOutboxSent(ctx, e.ID, p.config.Owner)
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Printf("[OutboxPublisher] lease lost before mark sent id=%d; leaving row to its new owner", e.ID)
+			continue
+		}
+		if err != nil {
+			log.Printf("[OutboxPublisher] mark sent failed id=%d: %v", e.ID, err)
+			p.retryLater(ctx, e, "mark sent failed: "+err.Error())
+			continue
+		}
+
+		log.Printf("[OutboxPublisher] published and marked sent id=%d channel=%s", e.ID, e.Channel)
+	}
+
+	return len(events), nil
+}
+
+// retryLater records a failed attempt and schedules the next one, or
+// dead-letters the event once it has used up its attempts.
+func (p *Publisher) retryLater(ctx context.Context, e models.OutboxEvent, errMsg string) {
+	attempt := e.Attempts + 1
+	retryIn := p.config.Retry.Backoff(attempt)
+
+	deadLettered, err := p.svc.IncrementOutboxAttempts(ctx, e.ID, errMsg, retryIn, p.config.Retry.MaxAttempts)
+	if err != nil {
+		log.Printf("[OutboxPublisher] record attempt failed id=%d: %v", e.ID, err)
+		return
+	}
+	if deadLettered {
+		log.Printf("[OutboxPublisher] dead-lettered id=%d after %d attempts: %s", e.ID, attempt, errMsg)
+	}
+}
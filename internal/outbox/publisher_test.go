@@ -0,0 +1,184 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"licenseplate-plugin/internal/database"
+	"licenseplate-plugin/internal/eventbus"
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/services"
+	"licenseplate-plugin/internal/storage"
+	"licenseplate-plugin/migrations"
+)
+
+// runConcurrentPublishers inserts n events on channel, drains them with
+// several publishers running in parallel and returns how often each payload
+// was published.
+func runConcurrentPublishers(t *testing.T, store storage.Store, channel string, n, publishers int) map[string]int {
+	t.Helper()
+	ctx := context.Background()
+	svc := services.NewLicensePlateService(store)
+
+	for i := 0; i < n; i++ {
+		if _, err := store.InsertOutboxEvent(ctx, channel, fmt.Sprintf("event-%d", i)); err != nil {
+			t.Fatalf("insert outbox event: %v", err)
+		}
+	}
+
+	var mu sync.Mutex
+	published := map[string]int{}
+	publish := func(ctx context.Context, ch, message string) error {
+		if ch != channel {
+			return nil
+		}
+		// Widen the window in which publishers overlap
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		published[message]++
+		mu.Unlock()
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < publishers; i++ {
+		p := NewPublisher(svc, eventbus.PublishFunc(publish), Config{
+			Owner:     fmt.Sprintf("publisher-%d", i),
+			BatchSize: 10,
+			Lease:     time.Minute,
+			Retry:     DefaultRetryPolicy(),
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				claimed, err := p.PublishBatch(ctx)
+				if err != nil {
+					t.Errorf("publish batch: %v", err)
+					return
+				}
+				if claimed == 0 {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return published
+}
+
+func assertPublishedOnce(t *testing.T, published map[string]int, n int) {
+	t.Helper()
+	if len(published) != n {
+		t.Fatalf("published %d distinct events, want %d", len(published), n)
+	}
+	for message, count := range published {
+		if count != 1 {
+			t.Errorf("%s published %d times, want 1", message, count)
+		}
+	}
+}
+
+func TestConcurrentPublishersPublishEachEventOnce(t *testing.T) {
+	store := storage.NewMemoryStore()
+
+	published := runConcurrentPublishers(t, store, "events", 200, 8)
+
+	assertPublishedOnce(t, published, 200)
+	for _, e := range store.OutboxEvents() {
+		if e.SentAt == nil {
+			t.Errorf("event %d was not marked sent", e.ID)
+		}
+	}
+}
+
+// TestConcurrentPublishersPostgres exercises the FOR UPDATE SKIP LOCKED claim
+// query. It needs a disposable database in TEST_DATABASE_URL.
+func TestConcurrentPublishersPostgres(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	db, err := database.NewDatabase(url, database.DefaultOptions())
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+
+	channel := fmt.Sprintf("test-outbox-%d", time.Now().UnixNano())
+	defer db.Execute(ctx, `DELETE FROM outbox_events WHERE channel = $1`, channel)
+
+	published := runConcurrentPublishers(t, storage.NewPostgresStore(db), channel, 200, 8)
+
+	assertPublishedOnce(t, published, 200)
+	var unsent int
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM outbox_events WHERE channel = $1 AND sent_at IS NULL`, channel).Scan(&unsent); err != nil {
+		t.Fatalf("count unsent: %v", err)
+	}
+	if unsent != 0 {
+		t.Errorf("%d events left unsent", unsent)
+	}
+}
+
+// TestMarkSentRequiresLease checks that a publisher whose lease expired
+// cannot mark a row sent once another publisher reclaimed it or an admin
+// discarded it.
+func TestMarkSentRequiresLease(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	svc := services.NewLicensePlateService(store)
+
+	reclaimed, err := store.InsertOutboxEvent(ctx, "events", "reclaimed")
+	if err != nil {
+		t.Fatalf("insert outbox event: %v", err)
+	}
+	// A negative lease has already expired when the second publisher claims
+	if claimed, err := svc.ClaimOutboxEvents(ctx, "slow", 10, -time.Second); err != nil || len(claimed) != 1 {
+		t.Fatalf("claim by slow publisher = %v, %v", claimed, err)
+	}
+	if claimed, err := svc.ClaimOutboxEvents(ctx, "fast", 10, time.Minute); err != nil || len(claimed) != 1 {
+		t.Fatalf("claim by fast publisher = %v, %v", claimed, err)
+	}
+	if err := svc.MarkOutboxSent(ctx, reclaimed, "slow"); err != storage.ErrNotFound {
+		t.Fatalf("mark sent with lost lease = %v, want %v", err, storage.ErrNotFound)
+	}
+	if err := svc.MarkOutboxSent(ctx, reclaimed, "fast"); err != nil {
+		t.Fatalf("mark sent by lease owner: %v", err)
+	}
+
+	discarded, err := store.InsertOutboxEvent(ctx, "events", "discarded")
+	if err != nil {
+		t.Fatalf("insert outbox event: %v", err)
+	}
+	if _, err := svc.ClaimOutboxEvents(ctx, "slow", 10, time.Minute); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if err := svc.DiscardOutboxEvent(ctx, discarded); err != nil {
+		t.Fatalf("discard: %v", err)
+	}
+	if err := svc.MarkOutboxSent(ctx, discarded, "slow"); err != storage.ErrNotFound {
+		t.Fatalf("mark sent after discard = %v, want %v", err, storage.ErrNotFound)
+	}
+	e, err := svc.GetOutboxEvent(ctx, discarded)
+	if err != nil {
+		t.Fatalf("get outbox event: %v", err)
+	}
+	if e.Status != models.OutboxStatusDiscarded || e.SentAt != nil {
+		t.Fatalf("discarded event = %+v, want it left discarded", e)
+	}
+}
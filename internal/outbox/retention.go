@@ -0,0 +1,97 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"licenseplate-plugin/internal/services"
+)
+
+const (
+	// DefaultRetention is used when RetentionConfig.Retention is not
+	// positive.
+	DefaultRetention = 7 * 24 * time.Hour
+	// DefaultRetentionBatchSize is used when RetentionConfig.BatchSize is
+	// not positive.
+	DefaultRetentionBatchSize = 500
+)
+
+// RetentionConfig controls how long sent events stay in outbox_events.
+type RetentionConfig struct {
+	Retention time.Duration // Sent events older than this are removed
+	BatchSize int           // Rows removed per statement, to keep locks short
+	Archive   bool          // Move rows to outbox_events_archive instead of deleting
+}
+
+// RetentionJob periodically removes sent outbox events past the retention
+// period in bounded batches.
+type RetentionJob struct {
+	svc    *services.LicensePlateService
+	config RetentionConfig
+}
+
+// NewRetentionJob creates the job. A Retention of zero or less would remove
+// events the moment they are sent, and a BatchSize of zero or less would
+// make every batch come back empty and full at once, so the defaults are
+// used instead.
+func NewRetentionJob(svc *services.LicensePlateService, config RetentionConfig) *RetentionJob {
+	if config.Retention <= 0 {
+		log.Printf("[OutboxRetention] invalid retention %s, using %s", config.Retention, DefaultRetention)
+		config.Retention = DefaultRetention
+	}
+	if config.BatchSize <= 0 {
+		log.Printf("[OutboxRetention] invalid batch size %d, using %d", config.BatchSize, DefaultRetentionBatchSize)
+		config.BatchSize = DefaultRetentionBatchSize
+	}
+	return &RetentionJob{
+		svc:    svc,
+		config: config,
+	}
+}
+
+// Start runs the job in a background goroutine every interval until ctx is
+// cancelled.
+func (j *RetentionJob) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Println("[OutboxRetention] context canceled, stopping retention job")
+				return
+			case <-ticker.C:
+				if _, err := j.Purge(ctx); err != nil {
+					log.Printf("[OutboxRetention] purge error: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Purge removes expired sent events batch by batch until none are left or
+// ctx is cancelled, and returns the number of rows removed.
+func (j *RetentionJob) Purge(ctx context.Context) (int64, error) {
+	var total int64
+	for ctx.Err() == nil {
+		removed, err := j.svc.PurgeSentOutboxEvents(ctx, j.config.Retention, j.config.BatchSize, j.config.Archive)
+		total += removed
+		if err != nil {
+			return total, err
+		}
+		if removed < int64(j.config.BatchSize) {
+			break
+		}
+	}
+
+	if total > 0 {
+		action := "deleted"
+		if j.config.Archive {
+			action = "archived"
+		}
+		log.Printf("[OutboxRetention] %s %d sent event(s) older than %s", action, total, j.config.Retention)
+	}
+	return total, ctx.Err()
+}
@@ -0,0 +1,37 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"licenseplate-plugin/internal/services"
+	"licenseplate-plugin/internal/storage"
+)
+
+func TestRetentionJobRejectsNonPositiveBatchSize(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		svc := services.NewLicensePlateService(storage.NewMemoryStore())
+		job := NewRetentionJob(svc, RetentionConfig{Retention: time.Hour, BatchSize: size})
+
+		// With the batch size taken as is, Purge would never return
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if _, err := job.Purge(ctx); err != nil {
+			t.Fatalf("batch size %d: purge = %v", size, err)
+		}
+		cancel()
+		if job.config.BatchSize != DefaultRetentionBatchSize {
+			t.Fatalf("batch size %d: job uses %d, want %d", size, job.config.BatchSize, DefaultRetentionBatchSize)
+		}
+	}
+}
+
+func TestRetentionJobRejectsNonPositiveRetention(t *testing.T) {
+	for _, retention := range []time.Duration{0, -time.Hour} {
+		svc := services.NewLicensePlateService(storage.NewMemoryStore())
+		job := NewRetentionJob(svc, RetentionConfig{Retention: retention, BatchSize: 10})
+		if job.config.Retention != DefaultRetention {
+			t.Fatalf("retention %s: job uses %s, want %s", retention, job.config.Retention, DefaultRetention)
+		}
+	}
+}
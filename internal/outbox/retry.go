@@ -0,0 +1,43 @@
+package outbox
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy schedules redelivery of events that failed to publish.
+type RetryPolicy struct {
+	BaseDelay   time.Duration // Delay after the first failure
+	MaxDelay    time.Duration // Upper bound for the delay before jitter
+	MaxAttempts int           // Attempts before the event is dead-lettered
+	Jitter      float64       // Random spread as a fraction of the delay (0-1)
+}
+
+// DefaultRetryPolicy retries for roughly two hours before dead-lettering.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		BaseDelay:   5 * time.Second,
+		MaxDelay:    30 * time.Minute,
+		MaxAttempts: 10,
+		Jitter:      0.2,
+	}
+}
+
+// Backoff returns the delay before retrying after the given failed attempt
+// (1 for the first failure). The delay doubles per attempt up to MaxDelay
+// and is then spread by ±Jitter so failed rows do not retry in lockstep.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		spread := float64(delay) * p.Jitter
+		delay += time.Duration(spread * (2*rand.Float64() - 1))
+	}
+	return delay
+}
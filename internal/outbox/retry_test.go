@@ -0,0 +1,107 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"licenseplate-plugin/internal/eventbus"
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/services"
+	"licenseplate-plugin/internal/storage"
+)
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 3, want: 4 * time.Second},
+		{attempt: 4, want: 8 * time.Second},
+		{attempt: 5, want: 10 * time.Second},
+		{attempt: 50, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := policy.Backoff(tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second, Jitter: 0.2}
+
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{attempt: 1, min: 800 * time.Millisecond, max: 1200 * time.Millisecond},
+		{attempt: 3, min: 3200 * time.Millisecond, max: 4800 * time.Millisecond},
+		// The cap applies before jitter, so capped delays still spread
+		{attempt: 20, min: 8 * time.Second, max: 12 * time.Second},
+	}
+	for _, tt := range tests {
+		spread := map[time.Duration]bool{}
+		for i := 0; i < 200; i++ {
+			got := policy.Backoff(tt.attempt)
+			if got < tt.min || got > tt.max {
+				t.Fatalf("Backoff(%d) = %s, want within [%s, %s]", tt.attempt, got, tt.min, tt.max)
+			}
+			spread[got] = true
+		}
+		if len(spread) < 2 {
+			t.Errorf("Backoff(%d) returned the same delay every time", tt.attempt)
+		}
+	}
+}
+
+func TestPublisherDeadLettersAtMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	svc := services.NewLicensePlateService(store)
+
+	id, err := store.InsertOutboxEvent(ctx, "events", "undeliverable")
+	if err != nil {
+		t.Fatalf("insert outbox event: %v", err)
+	}
+	publish := func(ctx context.Context, channel, message string) error {
+		return errors.New("broker unavailable")
+	}
+	// A zero delay makes each failed row claimable again straight away
+	p := NewPublisher(svc, eventbus.PublishFunc(publish), Config{
+		Owner:     "publisher",
+		BatchSize: 10,
+		Lease:     time.Minute,
+		Retry:     RetryPolicy{MaxAttempts: 3},
+	})
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		if claimed, err := p.PublishBatch(ctx); err != nil || claimed != 1 {
+			t.Fatalf("attempt %d claimed %d, %v; want 1", attempt, claimed, err)
+		}
+		e, err := svc.GetOutboxEvent(ctx, id)
+		if err != nil {
+			t.Fatalf("get outbox event: %v", err)
+		}
+		if e.Attempts != attempt || e.LastError != "broker unavailable" {
+			t.Fatalf("after attempt %d event = %+v", attempt, e)
+		}
+		want := models.OutboxStatusPending
+		if attempt == 3 {
+			want = models.OutboxStatusFailed
+		}
+		if e.Status != want {
+			t.Fatalf("after attempt %d status = %q, want %q", attempt, e.Status, want)
+		}
+	}
+
+	// A dead-lettered row is no longer claimed
+	if claimed, err := p.PublishBatch(ctx); err != nil || claimed != 0 {
+		t.Fatalf("claim after dead-lettering = %d, %v; want 0", claimed, err)
+	}
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"licenseplate-plugin/internal/models"
+)
+
+// DetectionResult is the outcome of one detection of a batch.
+type DetectionResult struct {
+	Processed bool  // False for a duplicate, or when Err is set
+	Err       error // Processing failed; the detection was not recorded
+}
+
+// ProcessDetectionBatch processes detections, such as a camera's offline
+// buffer, in the order the camera saw them rather than the order they were
+// sent. Each detection is processed in its own transaction, so a failure
+// affects only its own result. Results are returned in the order of
+// detections; nil entries are skipped and left with a zero result.
+func (s *LicensePlateService) ProcessDetectionBatch(ctx context.Context, in Ingest, detections []*models.Detection) []DetectionResult {
+	order := make([]int, 0, len(detections))
+	for i, d := range detections {
+		if d != nil {
+			order = append(order, i)
+		}
+	}
+	// Detections without a timestamp are stamped with the receive time, so
+	// they go last
+	sort.SliceStable(order, func(a, b int) bool {
+		ta, tb := detections[order[a]].Timestamp, detections[order[b]].Timestamp
+		if ta.IsZero() || tb.IsZero() {
+			return !ta.IsZero() && tb.IsZero()
+		}
+		return ta.Before(tb)
+	})
+
+	results := make([]DetectionResult, len(detections))
+	for _, i := range order {
+		if err := ctx.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Processed, results[i].Err = s.ProcessDetection(ctx, in, detections[i])
+	}
+	return results
+}
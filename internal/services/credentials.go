@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/storage"
+)
+
+// ErrInvalidCredential is returned for credential requests the service rejects.
+var ErrInvalidCredential = errors.New("invalid credential request")
+
+// ErrCredentialNotFound is returned for unknown, or no longer active, keys.
+var ErrCredentialNotFound = errors.New("webhook credential not found")
+
+// randomHex returns n random bytes, hex encoded.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// IssueWebhookCredential creates a key for a site, a camera, or a camera at
+// a site. The returned credential includes the secret, which cannot be
+// retrieved again.
+func (s *LicensePlateService) IssueWebhookCredential(ctx context.Context, req models.WebhookCredentialRequest) (*models.WebhookCredential, error) {
+	return s.issueWebhookCredential(ctx, s.store, req)
+}
+
+func (s *LicensePlateService) issueWebhookCredential(ctx context.Context, store storage.Store, req models.WebhookCredentialRequest) (*models.WebhookCredential, error) {
+	cred := &models.WebhookCredential{
+		ID:          "whk_" + randomHex(8),
+		Site:        strings.TrimSpace(req.Site),
+		CameraID:    strings.TrimSpace(req.CameraID),
+		Secret:      randomHex(32),
+		Description: req.Description,
+	}
+	if cred.Site == "" && cred.CameraID == "" {
+		return nil, fmt.Errorf("%w: site or camera_id is required", ErrInvalidCredential)
+	}
+
+	if err := store.InsertWebhookCredential(ctx, cred); err != nil {
+		return nil, err
+	}
+	log.Printf("[LicensePlateService] Issued webhook credential %s (site=%q camera=%q)", cred.ID, cred.Site, cred.CameraID)
+	return cred, nil
+}
+
+// ListWebhookCredentials lists keys without their secrets
+func (s *LicensePlateService) ListWebhookCredentials(ctx context.Context, filter storage.WebhookCredentialFilter) ([]models.WebhookCredential, error) {
+	return s.store.ListWebhookCredentials(ctx, filter)
+}
+
+// RevokeWebhookCredential disables a key immediately
+func (s *LicensePlateService) RevokeWebhookCredential(ctx context.Context, id string) error {
+	err := s.store.RevokeWebhookCredential(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrCredentialNotFound
+	}
+	if err == nil {
+		log.Printf("[LicensePlateService] Revoked webhook credential %s", id)
+	}
+	return err
+}
+
+// RotateWebhookCredential issues a new key with the same scope as id and
+// lets the old one expire after overlap, so cameras can switch over without
+// rejected requests. The new credential is returned with its secret.
+func (s *LicensePlateService) RotateWebhookCredential(ctx context.Context, id string, overlap time.Duration) (*models.WebhookCredential, error) {
+	if overlap < 0 {
+		return nil, fmt.Errorf("%w: overlap must not be negative", ErrInvalidCredential)
+	}
+
+	var rotated *models.WebhookCredential
+	err := s.store.WithinTx(ctx, func(tx storage.Store) error {
+		old, err := tx.GetWebhookCredential(ctx, id, false)
+		if errors.Is(err, storage.ErrNotFound) || (err == nil && !old.Active) {
+			return ErrCredentialNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		rotated, err = s.issueWebhookCredential(ctx, tx, models.WebhookCredentialRequest{
+			Site:        old.Site,
+			CameraID:    old.CameraID,
+			Description: old.Description,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = tx.ExpireWebhookCredential(ctx, id, overlap, rotated.ID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrCredentialNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[LicensePlateService] Rotated webhook credential %s to %s, old key valid for %s", id, rotated.ID, overlap)
+	return rotated, nil
+}
+
+// WebhookCredential returns an active key with its secret for verifying a
+// request, or ErrCredentialNotFound.
+func (s *LicensePlateService) WebhookCredential(ctx context.Context, id string) (*models.WebhookCredential, error) {
+	cred, err := s.store.GetWebhookCredential(ctx, id, true)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && !cred.Active) {
+		return nil, ErrCredentialNotFound
+	}
+	return cred, err
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/storage"
+)
+
+// outboxRecorder returns a function that returns the envelopes enqueued in
+// store since the recorder was created or last called.
+func outboxRecorder(t *testing.T, store *storage.MemoryStore) func() []models.EventEnvelope {
+	seen := len(store.OutboxEvents())
+	return func() []models.EventEnvelope {
+		t.Helper()
+		rows := store.OutboxEvents()
+		envelopes := make([]models.EventEnvelope, 0, len(rows)-seen)
+		for _, row := range rows[seen:] {
+			var env models.EventEnvelope
+			if err := json.Unmarshal([]byte(row.Payload), &env); err != nil {
+				t.Fatalf("decode outbox event %d: %v", row.ID, err)
+			}
+			envelopes = append(envelopes, env)
+		}
+		seen = len(rows)
+		return envelopes
+	}
+}
+
+// expectEvents checks the types of envelopes and decodes the data of each
+// into the matching element of data, skipping nil ones.
+func expectEvents(t *testing.T, envelopes []models.EventEnvelope, types []string, data ...interface{}) {
+	t.Helper()
+	got := make([]string, len(envelopes))
+	for i, env := range envelopes {
+		got[i] = env.Type
+	}
+	if !slices.Equal(got, types) {
+		t.Fatalf("events = %v, want %v", got, types)
+	}
+	for i, d := range data {
+		if d == nil {
+			continue
+		}
+		if err := json.Unmarshal(envelopes[i].Data, d); err != nil {
+			t.Fatalf("decode %s data: %v", types[i], err)
+		}
+	}
+}
+
+func TestRegistrationEvents(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	svc := NewLicensePlateService(store)
+	events := outboxRecorder(t, store)
+
+	if _, err := svc.ScanAndStore(ctx, models.ScanRequest{PlateNumber: "reg 1", GuestName: "Alice", RoomNumber: "101"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	var scanned models.LicensePlateRecord
+	expectEvents(t, events(), []string{models.EventLicensePlateScanned}, &scanned)
+	if scanned.PlateNumber != "REG1" || scanned.GuestName != "Alice" {
+		t.Fatalf("scanned = %+v, want REG1 for Alice", scanned)
+	}
+
+	if _, err := svc.ScanAndStore(ctx, models.ScanRequest{PlateNumber: "REG1", GuestName: "Bob", RoomNumber: "202"}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	var updated models.LicensePlateUpdatedEvent
+	expectEvents(t, events(), []string{models.EventLicensePlateScanned, models.EventLicensePlateUpdated}, nil, &updated)
+	if updated.GuestName != "Bob" || updated.Previous.GuestName != "Alice" || updated.Previous.RoomNumber != "101" {
+		t.Fatalf("updated = %+v, want Bob replacing Alice in 101", updated)
+	}
+
+	if err := svc.DeleteRecord(ctx, "reg 1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	var deleted models.LicensePlateDeletedEvent
+	expectEvents(t, events(), []string{models.EventLicensePlateDeleted}, &deleted)
+	if deleted.PlateNumber != "REG1" || deleted.GuestName != "Bob" || deleted.DeletedAt.IsZero() {
+		t.Fatalf("deleted = %+v, want Bob's registration with a deletion time", deleted)
+	}
+
+	// Deleting a missing plate fails without an event
+	if err := svc.DeleteRecord(ctx, "REG1"); err == nil {
+		t.Fatal("deleting a missing plate succeeded")
+	}
+	expectEvents(t, events(), []string{})
+}
+
+func TestDetectionEvents(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	svc := NewLicensePlateService(store)
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	if _, err := svc.ScanAndStore(ctx, models.ScanRequest{PlateNumber: "KNOWN1", GuestName: "Carol", VisitorType: "vip"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if _, err := svc.AddToWatchlist(ctx, models.WatchlistRequest{PlateNumber: "KNOWN1", Reason: "unpaid invoice"}); err != nil {
+		t.Fatalf("watchlist: %v", err)
+	}
+	events := outboxRecorder(t, store)
+
+	detect := func(plate, eventType string, at time.Time) {
+		t.Helper()
+		d := &models.Detection{Vendor: "xpots", EventType: eventType, PlateNumber: plate, Timestamp: at, Location: "Gate A", CameraID: "CAM-1"}
+		if _, err := svc.ProcessDetection(ctx, Ingest{Consumer: "test"}, d); err != nil {
+			t.Fatalf("process %s of %s: %v", eventType, plate, err)
+		}
+	}
+
+	// An unknown plate is recorded and announced once
+	detect("NEW1", "entry", base)
+	var entered models.VehicleEnteredEvent
+	expectEvents(t, events(), []string{models.EventVehicleEntered, models.EventVehicleUnknownDetected}, &entered)
+	if entered.Known || entered.PlateNumber != "NEW1" || entered.EventType != "entry" {
+		t.Fatalf("entered = %+v, want an unknown entry of NEW1", entered)
+	}
+	detect("NEW1", "exit", base.Add(45*time.Minute))
+	var exited models.VehicleExitedEvent
+	expectEvents(t, events(), []string{models.EventVehicleExited}, &exited)
+	if !exited.Known || exited.EntryTime == nil || !exited.EntryTime.Equal(base) || exited.StaySeconds != 45*60 {
+		t.Fatalf("exited = %+v, want a 45 minute stay from %s", exited, base)
+	}
+
+	// A registered, watchlisted plate
+	detect("KNOWN1", "entry", base)
+	var hit models.WatchlistHitEvent
+	entered = models.VehicleEnteredEvent{}
+	expectEvents(t, events(), []string{models.EventVehicleEntered, models.EventWatchlistHit}, &entered, &hit)
+	if !entered.Known || entered.GuestName != "Carol" || entered.VisitorType != "vip" || entered.AccessExpired {
+		t.Fatalf("entered = %+v, want Carol's vip registration", entered)
+	}
+	if hit.Reason != "unpaid invoice" || hit.PlateNumber != "KNOWN1" || hit.WatchlistedAt.IsZero() {
+		t.Fatalf("hit = %+v, want the watchlist entry for KNOWN1", hit)
+	}
+
+	// An exit without a preceding entry has no stay
+	detect("LONE1", "exit", base)
+	exited = models.VehicleExitedEvent{}
+	expectEvents(t, events(), []string{models.EventVehicleExited, models.EventVehicleUnknownDetected}, &exited)
+	if exited.EntryTime != nil || exited.StaySeconds != 0 {
+		t.Fatalf("exited = %+v, want no stay", exited)
+	}
+}
+
+func TestAccessExpiredEvents(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	svc := NewLicensePlateService(store)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	register := func(plate string, expires time.Time) {
+		t.Helper()
+		req := models.ScanRequest{PlateNumber: plate, GuestName: "Dave", VisitorType: "contractor", AccessExpiresAt: expires.Format(time.RFC3339)}
+		if _, err := svc.ScanAndStore(ctx, req); err != nil {
+			t.Fatalf("register %s: %v", plate, err)
+		}
+	}
+	sweep := func(want int) []models.EventEnvelope {
+		t.Helper()
+		events := outboxRecorder(t, store)
+		n, err := svc.NotifyExpiredAccess(ctx, 10)
+		if err != nil {
+			t.Fatalf("sweep: %v", err)
+		}
+		if n != want {
+			t.Fatalf("sweep found %d expired plates, want %d", n, want)
+		}
+		return events()
+	}
+
+	register("EXP1", now.Add(-time.Minute))
+	register("LATER1", now.Add(time.Hour))
+	var expired models.AccessExpiredEvent
+	expectEvents(t, sweep(1), []string{models.EventAccessExpired}, &expired)
+	if expired.PlateNumber != "EXP1" || expired.GuestName != "Dave" || !expired.AccessExpiresAt.Equal(now.Add(-time.Minute)) {
+		t.Fatalf("expired = %+v, want EXP1 expiring at %s", expired, now.Add(-time.Minute))
+	}
+
+	// Each expiry is announced once
+	expectEvents(t, sweep(0), []string{})
+
+	// Rescanning with the same expiry does not re-arm it
+	register("EXP1", now.Add(-time.Minute))
+	expectEvents(t, sweep(0), []string{})
+
+	// A new expiry re-arms it, even one that has already passed
+	register("EXP1", now.Add(-30*time.Second))
+	expired = models.AccessExpiredEvent{}
+	expectEvents(t, sweep(1), []string{models.EventAccessExpired}, &expired)
+	if !expired.AccessExpiresAt.Equal(now.Add(-30 * time.Second)) {
+		t.Fatalf("re-armed expiry at %s, want %s", expired.AccessExpiresAt, now.Add(-30*time.Second))
+	}
+
+	// Extending access and letting it run out again announces it again
+	register("EXP1", now.Add(time.Hour))
+	expectEvents(t, sweep(0), []string{})
+	register("EXP1", now.Add(-time.Second))
+	expectEvents(t, sweep(1), []string{models.EventAccessExpired})
+}
+
+// TestEventRolledBackWithWrite checks that an event enqueued inside a
+// transaction is discarded together with the write when the transaction
+// fails afterwards.
+func TestEventRolledBackWithWrite(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	svc := NewLicensePlateService(store)
+	failure := errors.New("fail after enqueue")
+
+	record := &models.LicensePlateRecord{PlateNumber: "TX1", GuestName: "Erin", CheckIn: time.Now().UTC()}
+	event := &models.ParkingEvent{PlateNumber: "TX1", EventType: "entry", EventTime: time.Now().UTC()}
+	err := store.WithinTx(ctx, func(tx storage.Store) error {
+		if err := tx.UpsertPlate(ctx, record); err != nil {
+			return err
+		}
+		if err := tx.InsertParkingEvent(ctx, event); err != nil {
+			return err
+		}
+		if err := svc.enqueueEvent(ctx, tx, models.EventLicensePlateScanned, record); err != nil {
+			return err
+		}
+		if n := len(store.OutboxEvents()); n != 1 {
+			t.Errorf("outbox inside the transaction has %d events, want 1", n)
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("WithinTx = %v, want %v", err, failure)
+	}
+
+	if events := store.OutboxEvents(); len(events) != 0 {
+		t.Errorf("outbox after rollback = %+v, want it empty", events)
+	}
+	if _, err := store.GetPlate(ctx, "TX1"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("plate after rollback: err = %v, want %v", err, storage.ErrNotFound)
+	}
+	if events, err := store.ListParkingEvents(ctx, "TX1"); err != nil || len(events) != 0 {
+		t.Errorf("parking events after rollback = %+v, %v; want none", events, err)
+	}
+}
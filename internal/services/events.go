@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/storage"
+)
+
+// DefaultEventSource is the CloudEvents source used when none is configured.
+const DefaultEventSource = "/licenseplate-plugin"
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context carrying id. Events enqueued with the
+// context are stamped with it so consumers can trace a chain of events back
+// to the request that started it.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation id stored in ctx, if any.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// NewEventID returns a random RFC 4122 version 4 UUID.
+func NewEventID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// SetEventSource sets the CloudEvents source stamped on events this service
+// enqueues. Call it before the service is used.
+func (s *LicensePlateService) SetEventSource(source string) {
+	s.eventSource = source
+}
+
+// newEnvelope wraps data in an event envelope of the given type, stamped
+// with a fresh id, the service's source and the correlation id from ctx.
+func (s *LicensePlateService) newEnvelope(ctx context.Context, eventType string, data interface{}) (*models.EventEnvelope, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s data: %w", eventType, err)
+	}
+
+	id := NewEventID()
+	correlationID := CorrelationID(ctx)
+	if correlationID == "" {
+		// The first event of a chain correlates to itself
+		correlationID = id
+	}
+
+	return &models.EventEnvelope{
+		SpecVersion:     models.EventSpecVersion,
+		ID:              id,
+		Source:          s.eventSource,
+		Type:            eventType,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		SchemaVersion:   models.EventSchemaVersion,
+		CorrelationID:   correlationID,
+		Data:            raw,
+	}, nil
+}
+
+// enqueueEvent writes an event to the outbox using the given store, so the
+// event commits or rolls back together with the caller's transaction.
+func (s *LicensePlateService) enqueueEvent(ctx context.Context, store storage.Store, eventType string, data interface{}) error {
+	envelope, err := s.newEnvelope(ctx, eventType, data)
+	if err != nil {
+		return err
+	}
+	return insertEnvelope(ctx, store, EventsChannel, envelope)
+}
+
+// insertEnvelope writes envelope to the outbox for channel.
+func insertEnvelope(ctx context.Context, store storage.Store, channel string, envelope *models.EventEnvelope) error {
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", envelope.Type, err)
+	}
+
+	if _, err := store.InsertOutboxEvent(ctx, channel, string(payload)); err != nil {
+		return fmt.Errorf("enqueue %s event: %w", envelope.Type, err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/storage"
+)
+
+// DefaultProcessedEventTTL is how long processed event ids are remembered
+// when no TTL is configured. It should exceed the longest redelivery delay
+// (outbox retries, pending stream entries).
+const DefaultProcessedEventTTL = 7 * 24 * time.Hour
+
+// SetProcessedEventTTL sets how long the processed-events ledger remembers
+// an event id. Call it before the service is used.
+func (s *LicensePlateService) SetProcessedEventTTL(ttl time.Duration) {
+	s.processedTTL = ttl
+}
+
+// DerivedEventID builds a stable event id from parts, for sources whose
+// events carry no id of their own. Equal parts yield equal ids.
+func DerivedEventID(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x1f")))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// DetectionEventID derives an id for a camera detection from the vendor,
+// camera, plate, direction and detection time. It returns "" when the
+// detection has no timestamp, since repeated detections could not be told
+// apart.
+func DetectionEventID(d *models.Detection) string {
+	if d.Timestamp.IsZero() {
+		return ""
+	}
+	return DerivedEventID(
+		d.Vendor,
+		d.CameraID,
+		d.Location,
+		normalizePlate(d.PlateNumber),
+		d.EventType,
+		d.Timestamp.UTC().Format(time.RFC3339Nano),
+	)
+}
+
+// markProcessed records eventID for consumer in the ledger through store,
+// so the record commits or rolls back with the caller's changes. It reports
+// false if the event was processed before. Events without an id cannot be
+// deduplicated and are always reported as new.
+func (s *LicensePlateService) markProcessed(ctx context.Context, store storage.Store, consumer, eventID string) (bool, error) {
+	if eventID == "" {
+		return true, nil
+	}
+	return store.MarkEventProcessed(ctx, consumer, eventID, s.processedTTL)
+}
+
+// webhookSignatureConsumer is the ledger consumer under which used webhook
+// signatures are recorded.
+const webhookSignatureConsumer = "webhook-signature"
+
+// ClaimWebhookSignature records a verified webhook signature in the ledger
+// for ttl, so every replica rejects it if it is replayed. It reports false
+// if the signature was already recorded.
+func (s *LicensePlateService) ClaimWebhookSignature(ctx context.Context, signature string, ttl time.Duration) (bool, error) {
+	return s.store.MarkEventProcessed(ctx, webhookSignatureConsumer, signature, ttl)
+}
+
+// PurgeProcessedEvents removes up to batchSize expired ledger entries and
+// returns the number removed.
+func (s *LicensePlateService) PurgeProcessedEvents(ctx context.Context, batchSize int) (int64, error) {
+	return s.store.PurgeProcessedEvents(ctx, batchSize)
+}
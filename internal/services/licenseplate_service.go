@@ -1,29 +1,43 @@
 package services
 
 import (
-	"database/sql"
+	"context"
 	"errors"
 	"fmt"
-	"licenseplate-plugin/internal/database"
 	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/storage"
 	"log"
 	"strings"
 	"time"
 )
 
+// EventsChannel is the event bus channel that outbox events are published to.
+const EventsChannel = "events"
+
 type LicensePlateService struct {
-	db *database.Database
+	store         storage.Store
+	eventSource   string
+	processedTTL  time.Duration
+	skewThreshold time.Duration
 }
 
-func NewLicensePlateService(db *database.Database) *LicensePlateService {
+func NewLicensePlateService(store storage.Store) *LicensePlateService {
 	return &LicensePlateService{
-		db: db,
+		store:         store,
+		eventSource:   DefaultEventSource,
+		processedTTL:  DefaultProcessedEventTTL,
+		skewThreshold: DefaultClockSkewThreshold,
 	}
 }
 
-func (s *LicensePlateService) ScanAndStore(req models.ScanRequest) (*models.LicensePlateRecord, error) {
+// normalizePlate uppercases a plate number and strips spaces
+func normalizePlate(plateNumber string) string {
+	return strings.ToUpper(strings.ReplaceAll(plateNumber, " ", ""))
+}
+
+func (s *LicensePlateService) ScanAndStore(ctx context.Context, req models.ScanRequest) (*models.LicensePlateRecord, error) {
 	// Normalize plate number (uppercase, remove spaces)
-	plateNumber := strings.ToUpper(strings.ReplaceAll(req.PlateNumber, " ", ""))
+	plateNumber := normalizePlate(req.PlateNumber)
 
 	if plateNumber == "" {
 		return nil, errors.New("plate number is required")
@@ -41,277 +55,117 @@ func (s *LicensePlateService) ScanAndStore(req models.ScanRequest) (*models.Lice
 		return nil, errors.New("invalid visitor type")
 	}
 
-	// Parse expiration time if provided
-	var expiresAt sql.NullTime
-	if req.AccessExpiresAt != "" {
-		parsedTime, err := time.Parse(time.RFC3339, req.AccessExpiresAt)
-		if err != nil {
-			return nil, errors.New("invalid access_expires_at format, use ISO 8601")
-		}
-		expiresAt = sql.NullTime{Time: parsedTime, Valid: true}
-	}
-
-	checkIn := time.Now()
-	query := `
-		INSERT INTO license_plates (plate_number, guest_name, room_number, check_in, vehicle_make, vehicle_model, notes, visitor_type, access_expires_at, purpose, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		ON CONFLICT (plate_number) 
-		DO UPDATE SET guest_name = $2, room_number = $3, check_in = $4, vehicle_make = $5, vehicle_model = $6, notes = $7, visitor_type = $8, access_expires_at = $9, purpose = $10, updated_at = NOW()
-		RETURNING id, created_at
-	`
-
-	var id int
-	var createdAt time.Time
-	row := s.db.QueryRow(query, plateNumber, req.GuestName, req.RoomNumber, checkIn, req.VehicleMake, req.VehicleModel, req.Notes, visitorType, expiresAt, req.Purpose, checkIn)
-	
-	if err := row.Scan(&id, &createdAt); err != nil {
-		log.Println("[LicensePlateService] Error inserting/updating record:", err)
-		return nil, errors.New("failed to store license plate record")
-	}
-
 	record := &models.LicensePlateRecord{
 		PlateNumber:  plateNumber,
 		GuestName:    req.GuestName,
 		RoomNumber:   req.RoomNumber,
-		CheckIn:      checkIn,
+		CheckIn:      time.Now().UTC(),
 		VehicleMake:  req.VehicleMake,
 		VehicleModel: req.VehicleModel,
 		Notes:        req.Notes,
 		VisitorType:  visitorType,
 		Purpose:      req.Purpose,
-		CreatedAt:    createdAt,
-	}
-	
-	if expiresAt.Valid {
-		record.AccessExpiresAt = expiresAt.Time
 	}
 
-	return record, nil
-}
-
-// scanLicensePlateRecord is a helper function to reduce duplicate code
-func scanLicensePlateRecord(scanner interface {
-	Scan(dest ...interface{}) error
-}) (*models.LicensePlateRecord, error) {
-	record := &models.LicensePlateRecord{}
-	var checkOut, expiresAt sql.NullTime
-	var roomNumber, vehicleMake, vehicleModel, notes, purpose sql.NullString
-
-	err := scanner.Scan(
-		&record.PlateNumber,
-		&record.GuestName,
-		&roomNumber,
-		&record.CheckIn,
-		&checkOut,
-		&vehicleMake,
-		&vehicleModel,
-		&notes,
-		&record.VisitorType,
-		&expiresAt,
-		&purpose,
-		&record.CreatedAt,
-	)
-	if err != nil {
-		return nil, err
+	// Parse expiration time if provided
+	if req.AccessExpiresAt != "" {
+		parsedTime, err := time.Parse(time.RFC3339, req.AccessExpiresAt)
+		if err != nil {
+			return nil, errors.New("invalid access_expires_at format, use ISO 8601")
+		}
+		record.AccessExpiresAt = parsedTime
 	}
 
-	// Handle nullable fields
-	if checkOut.Valid {
-		record.CheckOut = checkOut.Time
-	}
-	if roomNumber.Valid {
-		record.RoomNumber = roomNumber.String
-	}
-	if vehicleMake.Valid {
-		record.VehicleMake = vehicleMake.String
-	}
-	if vehicleModel.Valid {
-		record.VehicleModel = vehicleModel.String
-	}
-	if notes.Valid {
-		record.Notes = notes.String
-	}
-	if expiresAt.Valid {
-		record.AccessExpiresAt = expiresAt.Time
-	}
-	if purpose.Valid {
-		record.Purpose = purpose.String
+	err := s.store.WithinTx(ctx, func(tx storage.Store) error {
+		previous, err := tx.GetPlate(ctx, plateNumber)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		if err := tx.UpsertPlate(ctx, record); err != nil {
+			return err
+		}
+		if err := s.enqueueEvent(ctx, tx, models.EventLicensePlateScanned, record); err != nil {
+			return err
+		}
+		if previous == nil {
+			return nil
+		}
+		return s.enqueueEvent(ctx, tx, models.EventLicensePlateUpdated, models.LicensePlateUpdatedEvent{
+			LicensePlateRecord: *record,
+			Previous:           *previous,
+		})
+	})
+	if err != nil {
+		log.Println("[LicensePlateService] Error inserting/updating record:", err)
+		return nil, errors.New("failed to store license plate record")
 	}
 
 	return record, nil
 }
 
-// LogParkingEvent creates an entry/exit event record
-func (s *LicensePlateService) LogParkingEvent(plateNumber, eventType string, location, cameraID string, confidence float64, notes string) error {
-	query := `
-		INSERT INTO parking_events (plate_number, event_type, event_time, location, camera_id, confidence, notes)
-		VALUES ($1, $2, NOW(), $3, $4, $5, $6)
-	`
-	
-	_, err := s.db.Execute(query, plateNumber, eventType, location, cameraID, confidence, notes)
-	if err != nil {
+// LogParkingEvent creates an entry/exit event record. eventTime is when the
+// camera detected the vehicle; a zero eventTime means now.
+func (s *LicensePlateService) LogParkingEvent(ctx context.Context, plateNumber, eventType string, eventTime time.Time, location, cameraID string, confidence float64, notes string) error {
+	receivedAt := time.Now()
+	eventTime, skewed := s.eventTimes(eventTime, receivedAt)
+	return logParkingEvent(ctx, s.store, &models.ParkingEvent{
+		PlateNumber: plateNumber,
+		EventType:   eventType,
+		EventTime:   eventTime,
+		ReceivedAt:  receivedAt,
+		ClockSkew:   skewed,
+		Location:    location,
+		CameraID:    cameraID,
+		Confidence:  confidence,
+		Notes:       notes,
+	})
+}
+
+func logParkingEvent(ctx context.Context, store storage.Store, event *models.ParkingEvent) error {
+	if err := store.InsertParkingEvent(ctx, event); err != nil {
 		log.Printf("[LicensePlateService] Error logging parking event: %v", err)
 		return err
 	}
-	
-	log.Printf("Logged %s event for plate %s", eventType, plateNumber)
+
+	log.Printf("Logged %s event for plate %s", event.EventType, event.PlateNumber)
 	return nil
 }
 
 // GetParkingEvents retrieves all events for a specific license plate
-func (s *LicensePlateService) GetParkingEvents(plateNumber string) ([]models.ParkingEvent, error) {
-	plateNumber = strings.ToUpper(strings.ReplaceAll(plateNumber, " ", ""))
-	
-	query := `
-		SELECT id, plate_number, event_type, event_time, location, camera_id, confidence, notes, created_at
-		FROM parking_events
-		WHERE plate_number = $1
-		ORDER BY event_time DESC
-	`
-	
-	conn, err := s.db.GetConnection()
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Close()
-	
-	rows, err := conn.Query(query, plateNumber)
+func (s *LicensePlateService) GetParkingEvents(ctx context.Context, plateNumber string) ([]models.ParkingEvent, error) {
+	events, err := s.store.ListParkingEvents(ctx, normalizePlate(plateNumber))
 	if err != nil {
 		log.Printf("[LicensePlateService] Error querying parking events: %v", err)
 		return nil, err
 	}
-	defer rows.Close()
-	
-	events := make([]models.ParkingEvent, 0)
-	for rows.Next() {
-		var event models.ParkingEvent
-		var location, cameraID, notes sql.NullString
-		var confidence sql.NullFloat64
-		
-		err := rows.Scan(
-			&event.ID,
-			&event.PlateNumber,
-			&event.EventType,
-			&event.EventTime,
-			&location,
-			&cameraID,
-			&confidence,
-			&notes,
-			&event.CreatedAt,
-		)
-		if err != nil {
-			log.Printf("[LicensePlateService] Error scanning event row: %v", err)
-			continue
-		}
-		
-		if location.Valid {
-			event.Location = location.String
-		}
-		if cameraID.Valid {
-			event.CameraID = cameraID.String
-		}
-		if confidence.Valid {
-			event.Confidence = confidence.Float64
-		}
-		if notes.Valid {
-			event.Notes = notes.String
-		}
-		
-		events = append(events, event)
-	}
-	
 	return events, nil
 }
 
 // SearchFilters contains all search and filter parameters
 type SearchFilters struct {
-	Search       string // Search in plate_number or guest_name
-	VisitorType  string // Filter by visitor type
-	DateFrom     string // Filter by check_in >= date
-	DateTo       string // Filter by check_in <= date
-}
-
-func (s *LicensePlateService) GetAllRecords(filters SearchFilters) []*models.LicensePlateRecord {
-	// Build dynamic query based on filters
-	query := `
-		SELECT plate_number, guest_name, room_number, check_in, check_out, vehicle_make, vehicle_model, notes, visitor_type, access_expires_at, purpose, created_at
-		FROM license_plates
-		WHERE 1=1
-	`
-	
-	args := make([]interface{}, 0)
-	argIndex := 1
-	
-	// Add search filter (plate number or guest name)
-	if filters.Search != "" {
-		query += fmt.Sprintf(" AND (UPPER(plate_number) LIKE $%d OR UPPER(guest_name) LIKE $%d)", argIndex, argIndex)
-		args = append(args, "%"+strings.ToUpper(filters.Search)+"%")
-		argIndex++
-	}
-	
-	// Add visitor type filter
-	if filters.VisitorType != "" {
-		query += fmt.Sprintf(" AND visitor_type = $%d", argIndex)
-		args = append(args, filters.VisitorType)
-		argIndex++
-	}
-	
-	// Add date from filter
-	if filters.DateFrom != "" {
-		query += fmt.Sprintf(" AND check_in >= $%d", argIndex)
-		args = append(args, filters.DateFrom)
-		argIndex++
-	}
-	
-	// Add date to filter
-	if filters.DateTo != "" {
-		query += fmt.Sprintf(" AND check_in <= $%d", argIndex)
-		args = append(args, filters.DateTo)
-		argIndex++
-	}
-	
-	query += " ORDER BY created_at DESC"
-
-	conn, err := s.db.GetConnection()
-	if err != nil {
-		log.Println("[LicensePlateService] Error connecting to database:", err)
-		return []*models.LicensePlateRecord{}
-	}
-	defer conn.Close()
+	Search      string // Search in plate_number or guest_name
+	VisitorType string // Filter by visitor type
+	DateFrom    string // Filter by check_in >= date
+	DateTo      string // Filter by check_in <= date
+}
 
-	rows, err := conn.Query(query, args...)
+func (s *LicensePlateService) GetAllRecords(ctx context.Context, filters SearchFilters) []*models.LicensePlateRecord {
+	records, err := s.store.ListPlates(ctx, storage.PlateFilter{
+		Search:      filters.Search,
+		VisitorType: filters.VisitorType,
+		DateFrom:    filters.DateFrom,
+		DateTo:      filters.DateTo,
+	})
 	if err != nil {
 		log.Println("[LicensePlateService] Error querying records:", err)
 		return []*models.LicensePlateRecord{}
 	}
-	defer rows.Close()
-
-	records := make([]*models.LicensePlateRecord, 0)
-	for rows.Next() {
-		record, err := scanLicensePlateRecord(rows)
-		if err != nil {
-			log.Println("[LicensePlateService] Error scanning row:", err)
-			continue
-		}
-		records = append(records, record)
-	}
-
 	return records
 }
 
-func (s *LicensePlateService) GetRecord(plateNumber string) (*models.LicensePlateRecord, error) {
-	plateNumber = strings.ToUpper(strings.ReplaceAll(plateNumber, " ", ""))
-
-	query := `
-		SELECT plate_number, guest_name, room_number, check_in, check_out, vehicle_make, vehicle_model, notes, visitor_type, access_expires_at, purpose, created_at
-		FROM license_plates
-		WHERE plate_number = $1
-	`
-
-	row := s.db.QueryRow(query, plateNumber)
-	record, err := scanLicensePlateRecord(row)
-
-	if err == sql.ErrNoRows {
+func (s *LicensePlateService) GetRecord(ctx context.Context, plateNumber string) (*models.LicensePlateRecord, error) {
+	record, err := s.store.GetPlate(ctx, normalizePlate(plateNumber))
+	if errors.Is(err, storage.ErrNotFound) {
 		return nil, errors.New("record not found")
 	}
 	if err != nil {
@@ -322,147 +176,190 @@ func (s *LicensePlateService) GetRecord(plateNumber string) (*models.LicensePlat
 	return record, nil
 }
 
-func (s *LicensePlateService) DeleteRecord(plateNumber string) error {
-	plateNumber = strings.ToUpper(strings.ReplaceAll(plateNumber, " ", ""))
+func (s *LicensePlateService) DeleteRecord(ctx context.Context, plateNumber string) error {
+	plateNumber = normalizePlate(plateNumber)
 
-	query := `DELETE FROM license_plates WHERE plate_number = $1`
-
-	rowsAffected, err := s.db.Execute(query, plateNumber)
+	err := s.store.WithinTx(ctx, func(tx storage.Store) error {
+		record, err := tx.GetPlate(ctx, plateNumber)
+		if err != nil {
+			return err
+		}
+		if err := tx.DeletePlate(ctx, plateNumber); err != nil {
+			return err
+		}
+		return s.enqueueEvent(ctx, tx, models.EventLicensePlateDeleted, models.LicensePlateDeletedEvent{
+			LicensePlateRecord: *record,
+			DeletedAt:          time.Now().UTC(),
+		})
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return errors.New("record not found")
+	}
 	if err != nil {
 		log.Println("[LicensePlateService] Error deleting record:", err)
 		return errors.New("failed to delete record")
 	}
 
-	if rowsAffected == 0 {
-		return errors.New("record not found")
-	}
-
 	return nil
 }
 
-// Outbox: insert an event to be published reliably
-func (s *LicensePlateService) InsertOutboxEvent(channel, payload string) (int64, error) {
-	query := `
-		INSERT INTO outbox_events (channel, payload, attempts, created_at)
-		VALUES ($1, $2, 0, NOW())
-		RETURNING id
-	`
-	conn, err := s.db.GetConnection()
+// ClaimOutboxEvents leases a batch of unsent outbox events to the given
+// publisher instance so no other replica publishes them concurrently
+func (s *LicensePlateService) ClaimOutboxEvents(ctx context.Context, owner string, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	return s.store.ClaimOutboxEvents(ctx, owner, limit, lease)
+}
+
+// MarkOutboxSent marks the given outbox event as sent (sets sent_at) if
+// owner still holds its lease
+func (s *LicensePlateService) MarkOutboxSent(ctx context.Context, id int64, owner string) error {
+	return s.store.MarkOutboxSent(ctx, id, owner)
+}
+
+// IncrementOutboxAttempts records a failed publish attempt and schedules the
+// next one retryIn from now. It reports true when the event was
+// dead-lettered because it reached maxAttempts.
+func (s *LicensePlateService) IncrementOutboxAttempts(ctx context.Context, id int64, errMsg string, retryIn time.Duration, maxAttempts int) (bool, error) {
+	return s.store.IncrementOutboxAttempts(ctx, id, errMsg, retryIn, maxAttempts)
+}
+
+// ListOutboxEvents lists outbox events, e.g. the dead-lettered ones
+func (s *LicensePlateService) ListOutboxEvents(ctx context.Context, filter storage.OutboxFilter) ([]models.OutboxEvent, error) {
+	events, err := s.store.ListOutboxEvents(ctx, filter)
 	if err != nil {
-		return 0, err
+		log.Printf("[LicensePlateService] ListOutboxEvents error: %v", err)
+		return nil, errors.New("failed to list outbox events")
 	}
-	defer conn.Close()
+	return events, nil
+}
+
+// PurgeSentOutboxEvents removes one bounded batch of sent events older than
+// the retention period, archiving them first when archive is set
+func (s *LicensePlateService) PurgeSentOutboxEvents(ctx context.Context, retention time.Duration, batchSize int, archive bool) (int64, error) {
+	return s.store.PurgeSentOutboxEvents(ctx, retention, batchSize, archive)
+}
 
-	var id int64
-	err = conn.QueryRow(query, channel, payload).Scan(&id)
+// GetOutboxEvent returns a single outbox event including its last error
+func (s *LicensePlateService) GetOutboxEvent(ctx context.Context, id int64) (*models.OutboxEvent, error) {
+	event, err := s.store.GetOutboxEvent(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("%w: outbox event %d", storage.ErrNotFound, id)
+	}
 	if err != nil {
-		log.Printf("[LicensePlateService] InsertOutboxEvent error: %v", err)
-		return 0, err
-	}
-	return id, nil
-}
-
-// FetchPendingOutboxEvents fetches unsent outbox events up to limit
-func (s *LicensePlateService) FetchPendingOutboxEvents(limit int) ([]models.OutboxEvent, error) {
-	query := `
-		SELECT id, channel, payload, attempts, last_error, created_at, sent_at
-		FROM outbox_events
-		WHERE sent_at IS NULL
-		ORDER BY created_at ASC
-		LIMIT $1
-	`
-	conn, err := s.db.GetConnection()
+		log.Printf("[LicensePlateService] GetOutboxEvent error: %v", err)
+		return nil, errors.New("failed to retrieve outbox event")
+	}
+	return event, nil
+}
+
+// GetOutboxStats reports queue depth per status and the age of the oldest pending event
+func (s *LicensePlateService) GetOutboxStats(ctx context.Context) (*models.OutboxStats, error) {
+	stats, err := s.store.OutboxStats(ctx)
 	if err != nil {
-		return nil, err
+		log.Printf("[LicensePlateService] OutboxStats error: %v", err)
+		return nil, errors.New("failed to retrieve outbox stats")
 	}
-	defer conn.Close()
+	return stats, nil
+}
 
-	rows, err := conn.Query(query, limit)
+// RequeueOutboxEvent schedules an unsent outbox event for immediate
+// delivery with a fresh attempt budget
+func (s *LicensePlateService) RequeueOutboxEvent(ctx context.Context, id int64) error {
+	err := s.store.RequeueOutboxEvent(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%w: outbox event %d is missing or already sent", storage.ErrNotFound, id)
+	}
 	if err != nil {
-		return nil, err
+		log.Printf("[LicensePlateService] RequeueOutboxEvent error: %v", err)
+		return errors.New("failed to requeue outbox event")
 	}
-	defer rows.Close()
+	return nil
+}
 
-	events := []models.OutboxEvent{}
-	for rows.Next() {
-		var e models.OutboxEvent
-		var lastError sql.NullString
-		var sentAt sql.NullTime
-		if err := rows.Scan(&e.ID, &e.Channel, &e.Payload, &e.Attempts, &lastError, &e.CreatedAt, &sentAt); err != nil {
-			log.Printf("[LicensePlateService] FetchPendingOutboxEvents scan error: %v", err)
-			continue
-		}
-		if lastError.Valid {
-			e.LastError = lastError.String
-		}
-		if sentAt.Valid {
-			t := sentAt.Time
-			e.SentAt = &t
-		}
-		events = append(events, e)
+// DiscardOutboxEvent stops delivery of a pending or dead-lettered event
+func (s *LicensePlateService) DiscardOutboxEvent(ctx context.Context, id int64) error {
+	err := s.store.DiscardOutboxEvent(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%w: outbox event %d is missing or not pending/failed", storage.ErrNotFound, id)
 	}
-	return events, nil
+	if err != nil {
+		log.Printf("[LicensePlateService] DiscardOutboxEvent error: %v", err)
+		return errors.New("failed to discard outbox event")
+	}
+	return nil
 }
 
-// MarkOutboxSent marks the given outbox event as sent (sets sent_at)
-func (s *LicensePlateService) MarkOutboxSent(id int64) error {
-	query := `UPDATE outbox_events SET sent_at = NOW() WHERE id = $1`
-	_, err := s.db.Execute(query, id)
-	return err
+// SpillInboundEvent parks an inbound bus message the dispatcher has no room for
+func (s *LicensePlateService) SpillInboundEvent(ctx context.Context, channel, payload string) error {
+	return s.store.SpillInboundEvent(ctx, channel, payload)
 }
 
-// IncrementOutboxAttempts increments attempts and sets last_error
-func (s *LicensePlateService) IncrementOutboxAttempts(id int64, errMsg string) error {
-	query := `UPDATE outbox_events SET attempts = attempts + 1, last_error = $2 WHERE id = $1`
-	_, err := s.db.Execute(query, id, errMsg)
-	return err
+// TakeSpilledInboundEvents removes up to limit spilled messages for processing
+func (s *LicensePlateService) TakeSpilledInboundEvents(ctx context.Context, limit int) ([]models.SpilledEvent, error) {
+	return s.store.TakeSpilledInboundEvents(ctx, limit)
 }
 
+// RecordDeadLetter stores an inbound message that failed to dispatch
+func (s *LicensePlateService) RecordDeadLetter(ctx context.Context, dl *models.DeadLetter) error {
+	return s.store.UpsertDeadLetter(ctx, dl)
+}
 
-func (s *LicensePlateService) SearchByGuestName(guestName string) []*models.LicensePlateRecord {
-	query := `
-		SELECT plate_number, guest_name, room_number, check_in, check_out, vehicle_make, vehicle_model, notes, visitor_type, access_expires_at, purpose, created_at
-		FROM license_plates
-		WHERE LOWER(guest_name) LIKE LOWER($1)
-		ORDER BY created_at DESC
-	`
+// ListDeadLetters lists failed inbound messages, most recent failure first
+func (s *LicensePlateService) ListDeadLetters(ctx context.Context, filter storage.DeadLetterFilter) ([]models.DeadLetter, error) {
+	return s.store.ListDeadLetters(ctx, filter)
+}
 
-	conn, err := s.db.GetConnection()
-	if err != nil {
-		log.Println("[LicensePlateService] Error connecting to database:", err)
-		return []*models.LicensePlateRecord{}
+// GetDeadLetter returns one failed inbound message
+func (s *LicensePlateService) GetDeadLetter(ctx context.Context, id int64) (*models.DeadLetter, error) {
+	dl, err := s.store.GetDeadLetter(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("%w: dead letter %d", storage.ErrNotFound, id)
 	}
-	defer conn.Close()
+	return dl, err
+}
+
+// DeleteDeadLetter removes a failed inbound message once it has been
+// retried successfully or is no longer wanted
+func (s *LicensePlateService) DeleteDeadLetter(ctx context.Context, id int64) error {
+	err := s.store.DeleteDeadLetter(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%w: dead letter %d", storage.ErrNotFound, id)
+	}
+	return err
+}
 
-	searchTerm := "%" + guestName + "%"
-	rows, err := conn.Query(query, searchTerm)
+func (s *LicensePlateService) SearchByGuestName(ctx context.Context, guestName string) []*models.LicensePlateRecord {
+	records, err := s.store.ListPlates(ctx, storage.PlateFilter{GuestName: guestName})
 	if err != nil {
 		log.Println("[LicensePlateService] Error searching records:", err)
 		return []*models.LicensePlateRecord{}
 	}
-	defer rows.Close()
-
-	records := make([]*models.LicensePlateRecord, 0)
-	for rows.Next() {
-		record, err := scanLicensePlateRecord(rows)
-		if err != nil {
-			log.Println("[LicensePlateService] Error scanning row:", err)
-			continue
-		}
-		records = append(records, record)
-	}
-
 	return records
 }
 
-// ProcessXPOTSWebhook handles incoming webhook data from XPOTS system
-// Now logs events in parking_events table instead of overwriting check_in/check_out
-func (s *LicensePlateService) ProcessXPOTSWebhook(payload *models.XPOTSWebhookPayload) error {
+// Ingest describes how a detection reached the plugin.
+type Ingest struct {
+	Consumer string // Processed-events ledger consumer, e.g. the webhook or bus handler
+	EventID  string // Ledger event id; derived from the payload when empty
+	KeyID    string // Webhook credential that authenticated the detection, if any
+}
+
+// ProcessDetection records a camera detection as a parking event and
+// enqueues the resulting domain events. It reports true if the detection was
+// recorded, and false, without changing anything, if in.Consumer already
+// processed the same event id (in.EventID, or one derived with
+// DetectionEventID).
+func (s *LicensePlateService) ProcessDetection(ctx context.Context, in Ingest, payload *models.Detection) (bool, error) {
+	receivedAt := time.Now().UTC()
+
 	// Normalize plate number
-	plateNumber := strings.ToUpper(strings.ReplaceAll(payload.PlateNumber, " ", ""))
-	
+	plateNumber := normalizePlate(payload.PlateNumber)
+
 	if plateNumber == "" {
-		return errors.New("plate number is required")
+		return false, errors.New("plate number is required")
+	}
+	eventID := in.EventID
+	if eventID == "" {
+		eventID = DetectionEventID(payload)
 	}
 
 	// Determine event type
@@ -476,30 +373,201 @@ func (s *LicensePlateService) ProcessXPOTSWebhook(payload *models.XPOTSWebhookPa
 		log.Printf("Unknown event type '%s' for plate %s - treating as entry", payload.EventType, plateNumber)
 	}
 
-	// Log the parking event
-	notes := fmt.Sprintf("Auto-detected by XPOTS (confidence: %.2f%%)", payload.Confidence*100)
-	err := s.LogParkingEvent(plateNumber, eventType, payload.Location, payload.CameraID, payload.Confidence, notes)
-	if err != nil {
-		return err
-	}
+	// Record the detection, log the parking event, track unknown vehicles
+	// and enqueue the resulting events in one transaction
+	processed := false
+	err := s.store.WithinTx(ctx, func(tx storage.Store) error {
+		isNew, err := s.markProcessed(ctx, tx, in.Consumer, eventID)
+		if err != nil || !isNew {
+			return err
+		}
+		processed = true
+
+		eventTime, skewed := s.eventTimes(payload.Timestamp, receivedAt)
+		if skewed {
+			log.Printf("[LicensePlateService] Clock skew on camera %s: detection of %s at %s received at %s",
+				payload.CameraID, plateNumber, eventTime.Format(time.RFC3339), receivedAt.Format(time.RFC3339))
+		}
+
+		// Neither lookup sees this detection yet. previous is the event
+		// preceding it in event time, which is the latest one unless this
+		// detection arrived out of order
+		previous, err := latestParkingEvent(ctx, tx, plateNumber, time.Time{})
+		if err != nil {
+			return err
+		}
+		outOfOrder := previous != nil && previous.EventTime.After(eventTime)
+		if outOfOrder {
+			log.Printf("[LicensePlateService] Out-of-order %s of %s at %s; latest known event is at %s",
+				eventType, plateNumber, eventTime.Format(time.RFC3339), previous.EventTime.Format(time.RFC3339))
+			if previous, err = latestParkingEvent(ctx, tx, plateNumber, eventTime); err != nil {
+				return err
+			}
+		}
+
+		notes := fmt.Sprintf("Auto-detected by %s (confidence: %.2f%%)", strings.ToUpper(payload.Vendor), payload.Confidence*100)
+		event := &models.ParkingEvent{
+			PlateNumber:  plateNumber,
+			EventType:    eventType,
+			EventTime:    eventTime,
+			ReceivedAt:   receivedAt,
+			ClockSkew:    skewed,
+			OutOfOrder:   outOfOrder,
+			Location:     payload.Location,
+			CameraID:     payload.CameraID,
+			Confidence:   payload.Confidence,
+			Notes:        notes,
+			WebhookKeyID: in.KeyID,
+		}
+		if err := logParkingEvent(ctx, tx, event); err != nil {
+			return err
+		}
 
-	// Check if vehicle is registered in license_plates table
-	existingRecord, _ := s.GetRecord(plateNumber)
-	
-	if existingRecord == nil {
 		// Unknown vehicle - create a record for tracking
-		query := `
-			INSERT INTO license_plates (plate_number, guest_name, check_in, notes, visitor_type, created_at)
-			VALUES ($1, $2, $3, $4, $5, NOW())
-		`
-		guestName := "Unknown Guest (Auto-detected)"
-		notes := fmt.Sprintf("First detected at %s by camera %s", payload.Location, payload.CameraID)
-		
-		_, err := s.db.Execute(query, plateNumber, guestName, payload.Timestamp, notes, "visitor")
+		record := &models.LicensePlateRecord{
+			PlateNumber: plateNumber,
+			GuestName:   "Unknown Guest (Auto-detected)",
+			CheckIn:     eventTime,
+			Notes:       fmt.Sprintf("First detected at %s by camera %s", payload.Location, payload.CameraID),
+			VisitorType: "visitor",
+		}
+		created, err := tx.CreatePlateIfAbsent(ctx, record)
 		if err != nil {
 			log.Printf("[LicensePlateService] Error creating record for unknown vehicle %s: %v", plateNumber, err)
+			return err
+		}
+		if !created {
+			if record, err = tx.GetPlate(ctx, plateNumber); err != nil {
+				return err
+			}
+		}
+
+		if eventType == "exit" {
+			exited := models.VehicleExitedEvent{
+				ParkingEvent: *event,
+				Known:        !created,
+				GuestName:    record.GuestName,
+				VisitorType:  record.VisitorType,
+			}
+			// The stay starts at the entry preceding this exit in event
+			// time, which need not be the entry received last
+			if previous != nil && previous.EventType == "entry" {
+				exited.EntryTime = &previous.EventTime
+				exited.StaySeconds = int64(eventTime.Sub(previous.EventTime) / time.Second)
+			}
+			err = s.enqueueEvent(ctx, tx, models.EventVehicleExited, exited)
+		} else {
+			expired := !record.AccessExpiresAt.IsZero() && record.AccessExpiresAt.Before(event.EventTime)
+			err = s.enqueueEvent(ctx, tx, models.EventVehicleEntered, models.VehicleEnteredEvent{
+				ParkingEvent:  *event,
+				Known:         !created,
+				GuestName:     record.GuestName,
+				VisitorType:   record.VisitorType,
+				AccessExpired: expired,
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		if created {
+			err := s.enqueueEvent(ctx, tx, models.EventVehicleUnknownDetected, models.VehicleUnknownDetectedEvent{ParkingEvent: *event})
+			if err != nil {
+				return err
+			}
+		}
+
+		entry, err := tx.GetWatchlistEntry(ctx, plateNumber)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		log.Printf("[LicensePlateService] Watchlisted plate %s detected at %s: %s", plateNumber, payload.Location, entry.Reason)
+		return s.enqueueEvent(ctx, tx, models.EventWatchlistHit, models.WatchlistHitEvent{
+			ParkingEvent:  *event,
+			Reason:        entry.Reason,
+			WatchlistedAt: entry.CreatedAt,
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+	if !processed {
+		log.Printf("[LicensePlateService] Skipping duplicate detection %s for plate %s (%s)", eventID, plateNumber, in.Consumer)
+	}
+	return processed, nil
+}
+
+// NotifyExpiredAccess publishes access.expired for up to limit plates whose
+// access has expired since the last sweep, and returns how many it found.
+// Plates are marked notified in the same transaction, so each expiry is
+// announced once even with several replicas sweeping.
+func (s *LicensePlateService) NotifyExpiredAccess(ctx context.Context, limit int) (int, error) {
+	var count int
+	err := s.store.WithinTx(ctx, func(tx storage.Store) error {
+		records, err := tx.ClaimExpiredAccess(ctx, limit)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			err := s.enqueueEvent(ctx, tx, models.EventAccessExpired, models.AccessExpiredEvent{
+				PlateNumber:     record.PlateNumber,
+				GuestName:       record.GuestName,
+				RoomNumber:      record.RoomNumber,
+				VisitorType:     record.VisitorType,
+				AccessExpiresAt: record.AccessExpiresAt,
+			})
+			if err != nil {
+				return err
+			}
 		}
+		count = len(records)
+		return nil
+	})
+	return count, err
+}
+
+// AddToWatchlist adds a plate to the watchlist or updates its reason
+func (s *LicensePlateService) AddToWatchlist(ctx context.Context, req models.WatchlistRequest) (*models.WatchlistEntry, error) {
+	entry := &models.WatchlistEntry{
+		PlateNumber: normalizePlate(req.PlateNumber),
+		Reason:      strings.TrimSpace(req.Reason),
+	}
+	if entry.PlateNumber == "" {
+		return nil, errors.New("plate number is required")
+	}
+	if entry.Reason == "" {
+		return nil, errors.New("reason is required")
 	}
 
+	if err := s.store.UpsertWatchlistEntry(ctx, entry); err != nil {
+		log.Printf("[LicensePlateService] Error adding %s to watchlist: %v", entry.PlateNumber, err)
+		return nil, errors.New("failed to update watchlist")
+	}
+	return entry, nil
+}
+
+// GetWatchlist lists all watchlisted plates
+func (s *LicensePlateService) GetWatchlist(ctx context.Context) ([]models.WatchlistEntry, error) {
+	entries, err := s.store.ListWatchlist(ctx)
+	if err != nil {
+		log.Printf("[LicensePlateService] Error listing watchlist: %v", err)
+		return nil, errors.New("failed to retrieve watchlist")
+	}
+	return entries, nil
+}
+
+// RemoveFromWatchlist removes a plate from the watchlist
+func (s *LicensePlateService) RemoveFromWatchlist(ctx context.Context, plateNumber string) error {
+	err := s.store.DeleteWatchlistEntry(ctx, normalizePlate(plateNumber))
+	if errors.Is(err, storage.ErrNotFound) {
+		return errors.New("plate not on watchlist")
+	}
+	if err != nil {
+		log.Printf("[LicensePlateService] Error removing %s from watchlist: %v", plateNumber, err)
+		return errors.New("failed to update watchlist")
+	}
 	return nil
 }
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/storage"
+)
+
+// ErrInvalidReplay is returned by ReplayEvents for requests it rejects.
+var ErrInvalidReplay = errors.New("invalid replay request")
+
+// replayBatchSize is the number of events enqueued per transaction during a
+// replay, so long ranges never hold one huge transaction.
+const replayBatchSize = 500
+
+// ReplayEvents re-emits historical events matching req to req.Channel
+// through the outbox. licenseplate.scanned is rebuilt from license_plates
+// (one event per registration, at its check-in time) and vehicle.entered /
+// vehicle.exited from parking_events. Replayed envelopes carry the original
+// occurrence time, a shared correlation id and replay=true, so consumers
+// can tell them apart from live traffic.
+func (s *LicensePlateService) ReplayEvents(ctx context.Context, req models.ReplayRequest) (*models.ReplayResult, error) {
+	if req.Channel == "" {
+		return nil, fmt.Errorf("%w: channel is required", ErrInvalidReplay)
+	}
+	if !req.From.IsZero() && !req.To.IsZero() && !req.From.Before(req.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidReplay)
+	}
+	types := req.EventTypes
+	if len(types) == 0 {
+		types = models.ReplayableEvents
+	}
+	for _, t := range types {
+		if !slices.Contains(models.ReplayableEvents, t) {
+			return nil, fmt.Errorf("%w: event type %q cannot be replayed (want one of %v)", ErrInvalidReplay, t, models.ReplayableEvents)
+		}
+	}
+	req.PlateNumber = normalizePlate(req.PlateNumber)
+
+	result := &models.ReplayResult{
+		Channel:       req.Channel,
+		CorrelationID: NewEventID(),
+		Enqueued:      map[string]int{},
+	}
+	ctx = WithCorrelationID(ctx, result.CorrelationID)
+
+	// Registrations have no location, so a location filter excludes them
+	if slices.Contains(types, models.EventLicensePlateScanned) && req.Location == "" {
+		if err := s.replayRegistrations(ctx, req, result); err != nil {
+			return result, err
+		}
+	}
+
+	entered := slices.Contains(types, models.EventVehicleEntered)
+	exited := slices.Contains(types, models.EventVehicleExited)
+	if entered || exited {
+		filter := storage.ParkingEventFilter{
+			PlateNumber: req.PlateNumber,
+			Location:    req.Location,
+			From:        req.From,
+			To:          req.To,
+			Limit:       replayBatchSize,
+		}
+		if !exited {
+			filter.EventType = "entry"
+		} else if !entered {
+			filter.EventType = "exit"
+		}
+		if err := s.replayParkingEvents(ctx, req.Channel, filter, result); err != nil {
+			return result, err
+		}
+	}
+
+	log.Printf("[LicensePlateService] Replay %s enqueued %d event(s) to %s: %v",
+		result.CorrelationID, result.Total, result.Channel, result.Enqueued)
+	return result, nil
+}
+
+func (s *LicensePlateService) replayRegistrations(ctx context.Context, req models.ReplayRequest, result *models.ReplayResult) error {
+	filter := storage.PlateRangeFilter{
+		PlateNumber: req.PlateNumber,
+		From:        req.From,
+		To:          req.To,
+		Limit:       replayBatchSize,
+	}
+	for {
+		records, err := s.store.SearchPlates(ctx, filter)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+
+		err = s.store.WithinTx(ctx, func(tx storage.Store) error {
+			for _, rec := range records {
+				if err := s.enqueueReplay(ctx, tx, req.Channel, models.EventLicensePlateScanned, rec.CheckIn, rec); err != nil {
+					return err
+				}
+				result.Enqueued[models.EventLicensePlateScanned]++
+				result.Total++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		last := records[len(records)-1]
+		filter.AfterCheckIn, filter.AfterPlate = last.CheckIn, last.PlateNumber
+	}
+}
+
+func (s *LicensePlateService) replayParkingEvents(ctx context.Context, channel string, filter storage.ParkingEventFilter, result *models.ReplayResult) error {
+	registrations := map[string]*models.LicensePlateRecord{}
+	for {
+		events, err := s.store.SearchParkingEvents(ctx, filter)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		err = s.store.WithinTx(ctx, func(tx storage.Store) error {
+			for _, e := range events {
+				record, ok := registrations[e.PlateNumber]
+				if !ok {
+					var err error
+					record, err = tx.GetPlate(ctx, e.PlateNumber)
+					if errors.Is(err, storage.ErrNotFound) {
+						record = nil
+					} else if err != nil {
+						return err
+					}
+					registrations[e.PlateNumber] = record
+				}
+
+				eventType, data := replayedParkingEvent(e, record)
+				if err := s.enqueueReplay(ctx, tx, channel, eventType, e.EventTime, data); err != nil {
+					return err
+				}
+				result.Enqueued[eventType]++
+				result.Total++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		filter.AfterID = events[len(events)-1].ID
+	}
+}
+
+// replayedParkingEvent rebuilds the event published for a logged parking
+// event. The registration is its current state; the vehicle counts as
+// known if it was registered before the detection, as it did live.
+func replayedParkingEvent(e models.ParkingEvent, record *models.LicensePlateRecord) (string, interface{}) {
+	var known bool
+	var guestName, visitorType string
+	var expired bool
+	if record != nil {
+		known = record.CreatedAt.Before(e.EventTime)
+		guestName, visitorType = record.GuestName, record.VisitorType
+		expired = !record.AccessExpiresAt.IsZero() && record.AccessExpiresAt.Before(e.EventTime)
+	}
+
+	if e.EventType == "exit" {
+		return models.EventVehicleExited, models.VehicleExitedEvent{
+			ParkingEvent: e,
+			Known:        known,
+			GuestName:    guestName,
+			VisitorType:  visitorType,
+		}
+	}
+	return models.EventVehicleEntered, models.VehicleEnteredEvent{
+		ParkingEvent:  e,
+		Known:         known,
+		GuestName:     guestName,
+		VisitorType:   visitorType,
+		AccessExpired: expired,
+	}
+}
+
+// enqueueReplay writes a replayed event for channel to the outbox, stamped
+// with the time it originally occurred.
+func (s *LicensePlateService) enqueueReplay(ctx context.Context, store storage.Store, channel, eventType string, occurred time.Time, data interface{}) error {
+	envelope, err := s.newEnvelope(ctx, eventType, data)
+	if err != nil {
+		return err
+	}
+	envelope.Time = occurred.UTC()
+	envelope.Replay = true
+	return insertEnvelope(ctx, store, channel, envelope)
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/storage"
+)
+
+// TestReplayParkingEventsRange replays a range spanning several batches and
+// checks that every event in it is enqueued once, in id order, and nothing
+// outside it.
+func TestReplayParkingEventsRange(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	svc := NewLicensePlateService(store)
+	base := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Second)
+
+	const total = 2*replayBatchSize + 200
+	for i := 0; i < total; i++ {
+		eventType := "entry"
+		if i%2 == 1 {
+			eventType = "exit"
+		}
+		if err := svc.LogParkingEvent(ctx, "RPL1", eventType, base.Add(time.Duration(i)*time.Minute), "Gate A", "CAM-1", 0.9, ""); err != nil {
+			t.Fatalf("log event %d: %v", i, err)
+		}
+	}
+
+	// From is inclusive and To exclusive: events 100 to total-101
+	from, to := base.Add(100*time.Minute), base.Add((total-100)*time.Minute)
+	result, err := svc.ReplayEvents(ctx, models.ReplayRequest{
+		Channel:    "replay-test",
+		From:       from,
+		To:         to,
+		EventTypes: []string{models.EventVehicleEntered, models.EventVehicleExited},
+	})
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	want := total - 200
+	if result.Total != want || result.Enqueued[models.EventVehicleEntered] != want/2 || result.Enqueued[models.EventVehicleExited] != want/2 {
+		t.Fatalf("result = %+v, want %d events, half of each type", result, want)
+	}
+
+	lastID := 0
+	for _, row := range store.OutboxEvents() {
+		if row.Channel != "replay-test" {
+			continue
+		}
+		var env models.EventEnvelope
+		if err := json.Unmarshal([]byte(row.Payload), &env); err != nil {
+			t.Fatalf("decode outbox event %d: %v", row.ID, err)
+		}
+		var e models.ParkingEvent
+		if err := json.Unmarshal(env.Data, &e); err != nil {
+			t.Fatalf("decode %s data: %v", env.Type, err)
+		}
+		if e.ID <= lastID {
+			t.Fatalf("parking event %d replayed after %d", e.ID, lastID)
+		}
+		lastID = e.ID
+		if e.EventTime.Before(from) || !e.EventTime.Before(to) {
+			t.Fatalf("event at %s replayed, outside [%s, %s)", e.EventTime, from, to)
+		}
+		if !env.Replay || !env.Time.Equal(e.EventTime) || env.CorrelationID != result.CorrelationID {
+			t.Fatalf("envelope %+v is not marked as part of replay %s", env, result.CorrelationID)
+		}
+	}
+}
+
+func TestSearchParkingEventsPagesByAfterID(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	svc := NewLicensePlateService(store)
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	// Logged newest first, so id order differs from event time order
+	for i := 9; i >= 0; i-- {
+		if err := svc.LogParkingEvent(ctx, "PAGE1", "entry", base.Add(time.Duration(i)*time.Minute), "Gate A", "CAM-1", 0.9, ""); err != nil {
+			t.Fatalf("log event %d: %v", i, err)
+		}
+	}
+
+	filter := storage.ParkingEventFilter{From: base.Add(2 * time.Minute), To: base.Add(8 * time.Minute), Limit: 4}
+	var pages [][]models.ParkingEvent
+	for {
+		events, err := store.SearchParkingEvents(ctx, filter)
+		if err != nil {
+			t.Fatalf("search after %d: %v", filter.AfterID, err)
+		}
+		if len(events) == 0 {
+			break
+		}
+		pages = append(pages, events)
+		filter.AfterID = events[len(events)-1].ID
+	}
+
+	if len(pages) != 2 || len(pages[0]) != 4 || len(pages[1]) != 2 {
+		t.Fatalf("got pages of %v events, want 4 and 2", pageSizes(pages))
+	}
+	lastID := 0
+	for _, page := range pages {
+		for _, e := range page {
+			if e.ID <= lastID {
+				t.Fatalf("event %d returned after %d", e.ID, lastID)
+			}
+			lastID = e.ID
+		}
+	}
+}
+
+func pageSizes(pages [][]models.ParkingEvent) []int {
+	sizes := make([]int, len(pages))
+	for i, p := range pages {
+		sizes[i] = len(p)
+	}
+	return sizes
+}
+
+// TestReplayRegistrationsRange pages through more registrations than one
+// batch, several sharing a check-in time, with a range given in a non-UTC
+// offset.
+func TestReplayRegistrationsRange(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	svc := NewLicensePlateService(store)
+	base := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Second)
+
+	// Three plates per minute, so pages split plates with equal check-ins
+	const total = replayBatchSize + 301
+	for i := 0; i < total; i++ {
+		rec := &models.LicensePlateRecord{
+			PlateNumber: fmt.Sprintf("REG%04d", i),
+			GuestName:   "Guest",
+			VisitorType: "guest",
+			CheckIn:     base.Add(time.Duration(i/3) * time.Minute),
+		}
+		if err := store.UpsertPlate(ctx, rec); err != nil {
+			t.Fatalf("register %s: %v", rec.PlateNumber, err)
+		}
+	}
+
+	// Minutes 10 to 209: plates 30 to 629
+	plus2 := time.FixedZone("+02:00", 2*60*60)
+	from, to := base.Add(10*time.Minute).In(plus2), base.Add(210*time.Minute).In(plus2)
+	result, err := svc.ReplayEvents(ctx, models.ReplayRequest{
+		Channel:    "replay-test",
+		From:       from,
+		To:         to,
+		EventTypes: []string{models.EventLicensePlateScanned},
+	})
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if result.Total != 600 {
+		t.Fatalf("replayed %d registrations, want 600", result.Total)
+	}
+
+	seen := map[string]bool{}
+	var last time.Time
+	for _, row := range store.OutboxEvents() {
+		var env models.EventEnvelope
+		if err := json.Unmarshal([]byte(row.Payload), &env); err != nil {
+			t.Fatalf("decode outbox event %d: %v", row.ID, err)
+		}
+		var rec models.LicensePlateRecord
+		if err := json.Unmarshal(env.Data, &rec); err != nil {
+			t.Fatalf("decode %s data: %v", env.Type, err)
+		}
+		if seen[rec.PlateNumber] {
+			t.Fatalf("%s replayed twice", rec.PlateNumber)
+		}
+		seen[rec.PlateNumber] = true
+		if rec.CheckIn.Before(from) || !rec.CheckIn.Before(to) || rec.CheckIn.Before(last) {
+			t.Fatalf("%s checked in at %s replayed out of range or order", rec.PlateNumber, rec.CheckIn)
+		}
+		last = rec.CheckIn
+	}
+	if !seen["REG0030"] || !seen["REG0629"] || seen["REG0029"] || seen["REG0630"] {
+		t.Fatalf("range boundaries not respected")
+	}
+}
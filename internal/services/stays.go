@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/storage"
+)
+
+// DefaultClockSkewThreshold is how far a camera's detection time may be from
+// the receive time before the parking event is flagged, when no threshold
+// is configured.
+const DefaultClockSkewThreshold = 5 * time.Minute
+
+// SetClockSkewThreshold sets how far a detection's timestamp may differ
+// from the time it was received before its parking event is flagged with
+// ClockSkew. Call it before the service is used.
+func (s *LicensePlateService) SetClockSkewThreshold(d time.Duration) {
+	s.skewThreshold = d
+}
+
+// eventTimes returns the event time to store for a detection reported at
+// detected and received at receivedAt, and whether the two are further
+// apart than the skew threshold. Detections without a timestamp take the
+// receive time. Skewed timestamps are still stored as reported, but in UTC,
+// since event times are compared and ordered in a column without a time
+// zone; the flag lets consumers discount them.
+func (s *LicensePlateService) eventTimes(detected, receivedAt time.Time) (time.Time, bool) {
+	if detected.IsZero() {
+		return receivedAt, false
+	}
+	skew := receivedAt.Sub(detected)
+	return detected.UTC(), skew > s.skewThreshold || skew < -s.skewThreshold
+}
+
+// latestParkingEvent returns the plate's newest event at or before at (any
+// time when at is zero), or nil if there is none.
+func latestParkingEvent(ctx context.Context, store storage.Store, plateNumber string, at time.Time) (*models.ParkingEvent, error) {
+	event, err := store.LatestParkingEvent(ctx, plateNumber, at)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	return event, err
+}
+
+// GetStays pairs a plate's entries and exits into stays by event time, so
+// detections received out of order still form the right visits. Stays are
+// returned newest first.
+func (s *LicensePlateService) GetStays(ctx context.Context, plateNumber string) ([]models.Stay, error) {
+	events, err := s.store.ListParkingEvents(ctx, normalizePlate(plateNumber))
+	if err != nil {
+		log.Printf("[LicensePlateService] Error querying parking events: %v", err)
+		return nil, err
+	}
+	return buildStays(events), nil
+}
+
+// buildStays pairs events, ordered newest first, into stays. An entry
+// followed by another entry is kept as a stay without an exit, and an exit
+// without a preceding entry as a stay without an entry.
+func buildStays(events []models.ParkingEvent) []models.Stay {
+	stays := make([]models.Stay, 0)
+	var open *models.Stay
+	for i := len(events) - 1; i >= 0; i-- {
+		e := &events[i]
+		switch {
+		case e.EventType == "entry":
+			if open != nil {
+				stays = append(stays, *open)
+			}
+			open = &models.Stay{PlateNumber: e.PlateNumber, Entry: e}
+		case open != nil:
+			open.Exit = e
+			open.DurationSeconds = int64(e.EventTime.Sub(open.Entry.EventTime) / time.Second)
+			stays = append(stays, *open)
+			open = nil
+		default:
+			stays = append(stays, models.Stay{PlateNumber: e.PlateNumber, Exit: e})
+		}
+	}
+	if open != nil {
+		stays = append(stays, *open)
+	}
+	slices.Reverse(stays)
+	return stays
+}
+
+// GetOccupancy returns the vehicles currently parked: those whose latest
+// detection by event time is an entry. A delayed entry that predates the
+// vehicle's recorded exit does not count it as parked again.
+func (s *LicensePlateService) GetOccupancy(ctx context.Context) (*models.Occupancy, error) {
+	parked, err := s.store.ListParkedVehicles(ctx)
+	if err != nil {
+		log.Printf("[LicensePlateService] Error querying occupancy: %v", err)
+		return nil, err
+	}
+
+	occupancy := &models.Occupancy{
+		Count:      len(parked),
+		ByLocation: make(map[string]int),
+		Vehicles:   parked,
+	}
+	for _, e := range parked {
+		occupancy.ByLocation[e.Location]++
+	}
+	return occupancy, nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/internal/storage"
+)
+
+// TestOutOfOrderDetections delivers a visit's exit before its entry, as a
+// camera flushing its buffer might, and checks that stays and occupancy
+// follow the camera's timestamps rather than the arrival order.
+func TestOutOfOrderDetections(t *testing.T) {
+	ctx := context.Background()
+	svc := NewLicensePlateService(storage.NewMemoryStore())
+	base := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+
+	detect := func(eventType string, at time.Time) {
+		t.Helper()
+		d := &models.Detection{Vendor: "xpots", EventType: eventType, PlateNumber: "ooo 1", Timestamp: at, Location: "Gate A", CameraID: "CAM-1"}
+		if _, err := svc.ProcessDetection(ctx, Ingest{Consumer: "test"}, d); err != nil {
+			t.Fatalf("process %s at %s: %v", eventType, at, err)
+		}
+	}
+	occupancy := func() int {
+		t.Helper()
+		o, err := svc.GetOccupancy(ctx)
+		if err != nil {
+			t.Fatalf("occupancy: %v", err)
+		}
+		return o.Count
+	}
+
+	detect("exit", base.Add(90*time.Minute))
+	detect("entry", base) // Delayed entry of the same visit
+	if n := occupancy(); n != 0 {
+		t.Fatalf("occupancy after late entry = %d, want 0", n)
+	}
+
+	detect("entry", base.Add(100*time.Minute))
+	if n := occupancy(); n != 1 {
+		t.Fatalf("occupancy after new entry = %d, want 1", n)
+	}
+
+	stays, err := svc.GetStays(ctx, "OOO1")
+	if err != nil {
+		t.Fatalf("stays: %v", err)
+	}
+	if len(stays) != 2 {
+		t.Fatalf("got %d stays, want 2: %+v", len(stays), stays)
+	}
+	if stays[0].Exit != nil || !stays[0].Entry.EventTime.Equal(base.Add(100*time.Minute)) {
+		t.Fatalf("current stay = %+v, want an open stay from the last entry", stays[0])
+	}
+	if stays[1].Entry == nil || stays[1].Exit == nil || stays[1].DurationSeconds != 90*60 {
+		t.Fatalf("first stay = %+v, want 90 minutes", stays[1])
+	}
+	if !stays[1].Entry.OutOfOrder || stays[1].Exit.OutOfOrder {
+		t.Fatalf("out-of-order flags: entry %v, exit %v; want true, false", stays[1].Entry.OutOfOrder, stays[1].Exit.OutOfOrder)
+	}
+
+	// All three camera timestamps are far from their receive time
+	if !stays[1].Entry.ClockSkew || stays[1].Entry.ReceivedAt.Equal(stays[1].Entry.EventTime) {
+		t.Fatalf("entry received %s for event at %s not flagged", stays[1].Entry.ReceivedAt, stays[1].Entry.EventTime)
+	}
+}
+
+// TestDetectionTimesStoredInUTC checks that a camera reporting local time
+// with an offset gets its event time stored in UTC, so it orders correctly
+// against detections reported in UTC.
+func TestDetectionTimesStoredInUTC(t *testing.T) {
+	ctx := context.Background()
+	svc := NewLicensePlateService(storage.NewMemoryStore())
+	base := time.Now().Add(-2 * time.Hour).UTC().Truncate(time.Second)
+	plus2 := time.FixedZone("+02:00", 2*60*60)
+
+	entry := &models.Detection{Vendor: "xpots", EventType: "entry", PlateNumber: "TZ 1", Timestamp: base, CameraID: "CAM-UTC"}
+	// Wall clock 90 minutes ahead of the entry's, but 30 minutes before it
+	exit := &models.Detection{Vendor: "xpots", EventType: "exit", PlateNumber: "TZ 1", Timestamp: base.Add(-30 * time.Minute).In(plus2), CameraID: "CAM-CEST"}
+	for _, d := range []*models.Detection{entry, exit} {
+		if _, err := svc.ProcessDetection(ctx, Ingest{Consumer: "test"}, d); err != nil {
+			t.Fatalf("process %s: %v", d.EventType, err)
+		}
+	}
+
+	events, err := svc.store.ListParkingEvents(ctx, "TZ1")
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(events) != 2 || events[0].EventType != "entry" || events[1].EventType != "exit" {
+		t.Fatalf("events = %+v, want the entry newest", events)
+	}
+	exitEvent := events[1]
+	if exitEvent.EventTime.Location() != time.UTC || exitEvent.ReceivedAt.Location() != time.UTC {
+		t.Fatalf("exit stored at %s, received %s; want UTC", exitEvent.EventTime, exitEvent.ReceivedAt)
+	}
+	if !exitEvent.EventTime.Equal(exit.Timestamp) || !exitEvent.OutOfOrder {
+		t.Fatalf("exit event time %s, out of order %v; want %s and true", exitEvent.EventTime, exitEvent.OutOfOrder, exit.Timestamp.UTC())
+	}
+}
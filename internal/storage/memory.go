@@ -0,0 +1,904 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"licenseplate-plugin/internal/models"
+)
+
+// MemoryStore is an in-process Store for tests and local development.
+// Transactions are serialized and roll back by restoring a snapshot, so
+// writes made outside WithinTx while a transaction fails may be lost.
+type MemoryStore struct {
+	mu   sync.Mutex
+	txMu sync.Mutex
+
+	plates       map[string]models.LicensePlateRecord
+	notified     map[string]time.Time // Plate -> access_expires_at announced
+	watchlist    map[string]models.WatchlistEntry
+	events       []models.ParkingEvent
+	outbox       []models.OutboxEvent
+	archive      []models.OutboxEvent
+	spill        []models.SpilledEvent
+	processed    map[processedKey]time.Time // Ledger entry -> expiry
+	deadLetters  []models.DeadLetter
+	credentials  map[string]models.WebhookCredential
+	nextEventID  int
+	nextOutboxID int64
+	nextSpillID  int64
+	nextDeadID   int64
+}
+
+type processedKey struct {
+	consumer, eventID string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		plates:      map[string]models.LicensePlateRecord{},
+		notified:    map[string]time.Time{},
+		watchlist:   map[string]models.WatchlistEntry{},
+		processed:   map[processedKey]time.Time{},
+		credentials: map[string]models.WebhookCredential{},
+	}
+}
+
+// memorySnapshot is the state restored when a transaction rolls back.
+type memorySnapshot struct {
+	plates       map[string]models.LicensePlateRecord
+	notified     map[string]time.Time
+	watchlist    map[string]models.WatchlistEntry
+	events       []models.ParkingEvent
+	outbox       []models.OutboxEvent
+	archive      []models.OutboxEvent
+	spill        []models.SpilledEvent
+	processed    map[processedKey]time.Time
+	deadLetters  []models.DeadLetter
+	credentials  map[string]models.WebhookCredential
+	nextEventID  int
+	nextOutboxID int64
+	nextSpillID  int64
+	nextDeadID   int64
+}
+
+func (s *MemoryStore) snapshot() memorySnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return memorySnapshot{
+		plates:       maps.Clone(s.plates),
+		notified:     maps.Clone(s.notified),
+		watchlist:    maps.Clone(s.watchlist),
+		events:       append([]models.ParkingEvent(nil), s.events...),
+		outbox:       append([]models.OutboxEvent(nil), s.outbox...),
+		archive:      append([]models.OutboxEvent(nil), s.archive...),
+		spill:        append([]models.SpilledEvent(nil), s.spill...),
+		processed:    maps.Clone(s.processed),
+		deadLetters:  append([]models.DeadLetter(nil), s.deadLetters...),
+		credentials:  maps.Clone(s.credentials),
+		nextEventID:  s.nextEventID,
+		nextOutboxID: s.nextOutboxID,
+		nextSpillID:  s.nextSpillID,
+		nextDeadID:   s.nextDeadID,
+	}
+}
+
+func (s *MemoryStore) restore(snap memorySnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.plates = snap.plates
+	s.notified = snap.notified
+	s.watchlist = snap.watchlist
+	s.events = snap.events
+	s.outbox = snap.outbox
+	s.archive = snap.archive
+	s.spill = snap.spill
+	s.processed = snap.processed
+	s.deadLetters = snap.deadLetters
+	s.credentials = snap.credentials
+	s.nextEventID = snap.nextEventID
+	s.nextOutboxID = snap.nextOutboxID
+	s.nextSpillID = snap.nextSpillID
+	s.nextDeadID = snap.nextDeadID
+}
+
+func (s *MemoryStore) WithinTx(ctx context.Context, fn func(tx Store) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	snap := s.snapshot()
+	tx := &memoryTx{MemoryStore: s}
+	if err := fn(tx); err != nil {
+		s.restore(snap)
+		return err
+	}
+	return nil
+}
+
+// memoryTx marks a MemoryStore as already inside WithinTx so nested calls
+// join the running transaction instead of deadlocking on txMu.
+type memoryTx struct {
+	*MemoryStore
+}
+
+func (t *memoryTx) WithinTx(ctx context.Context, fn func(tx Store) error) error {
+	return fn(t)
+}
+
+func (s *MemoryStore) UpsertPlate(ctx context.Context, rec *models.LicensePlateRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.plates[rec.PlateNumber]; ok {
+		rec.CreatedAt = existing.CreatedAt
+	} else if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = rec.CheckIn
+	}
+	s.plates[rec.PlateNumber] = *rec
+	return nil
+}
+
+func (s *MemoryStore) CreatePlateIfAbsent(ctx context.Context, rec *models.LicensePlateRecord) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.plates[rec.PlateNumber]; ok {
+		return false, nil
+	}
+	rec.CreatedAt = time.Now()
+	s.plates[rec.PlateNumber] = *rec
+	return true, nil
+}
+
+func (s *MemoryStore) GetPlate(ctx context.Context, plateNumber string) (*models.LicensePlateRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.plates[plateNumber]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &rec, nil
+}
+
+func (s *MemoryStore) ListPlates(ctx context.Context, filter PlateFilter) ([]*models.LicensePlateRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from, hasFrom := parseFilterDate(filter.DateFrom)
+	to, hasTo := parseFilterDate(filter.DateTo)
+
+	records := make([]*models.LicensePlateRecord, 0)
+	for _, rec := range s.plates {
+		if filter.PlateNumber != "" && rec.PlateNumber != filter.PlateNumber {
+			continue
+		}
+		if filter.Search != "" {
+			term := strings.ToUpper(filter.Search)
+			if !strings.Contains(strings.ToUpper(rec.PlateNumber), term) && !strings.Contains(strings.ToUpper(rec.GuestName), term) {
+				continue
+			}
+		}
+		if filter.GuestName != "" && !strings.Contains(strings.ToLower(rec.GuestName), strings.ToLower(filter.GuestName)) {
+			continue
+		}
+		if filter.VisitorType != "" && rec.VisitorType != filter.VisitorType {
+			continue
+		}
+		if hasFrom && rec.CheckIn.Before(from) {
+			continue
+		}
+		if hasTo && rec.CheckIn.After(to) {
+			continue
+		}
+		rec := rec
+		records = append(records, &rec)
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].CreatedAt.After(records[j].CreatedAt) })
+	return records, nil
+}
+
+func (s *MemoryStore) SearchPlates(ctx context.Context, filter PlateRangeFilter) ([]*models.LicensePlateRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := func(a, b *models.LicensePlateRecord) bool {
+		if !a.CheckIn.Equal(b.CheckIn) {
+			return a.CheckIn.Before(b.CheckIn)
+		}
+		return a.PlateNumber < b.PlateNumber
+	}
+	after := &models.LicensePlateRecord{CheckIn: filter.AfterCheckIn, PlateNumber: filter.AfterPlate}
+
+	records := make([]*models.LicensePlateRecord, 0)
+	for _, rec := range s.plates {
+		if filter.PlateNumber != "" && rec.PlateNumber != filter.PlateNumber {
+			continue
+		}
+		if !filter.From.IsZero() && rec.CheckIn.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !rec.CheckIn.Before(filter.To) {
+			continue
+		}
+		if filter.AfterPlate != "" && !before(after, &rec) {
+			continue
+		}
+		rec := rec
+		records = append(records, &rec)
+	}
+
+	sort.Slice(records, func(i, j int) bool { return before(records[i], records[j]) })
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[:filter.Limit]
+	}
+	return records, nil
+}
+
+func (s *MemoryStore) DeletePlate(ctx context.Context, plateNumber string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.plates[plateNumber]; !ok {
+		return ErrNotFound
+	}
+	delete(s.plates, plateNumber)
+	delete(s.notified, plateNumber)
+	return nil
+}
+
+func (s *MemoryStore) ClaimExpiredAccess(ctx context.Context, limit int) ([]*models.LicensePlateRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var records []*models.LicensePlateRecord
+	for plate, rec := range s.plates {
+		expires := rec.AccessExpiresAt
+		if expires.IsZero() || expires.After(now) {
+			continue
+		}
+		// A different expiry than the announced one re-arms the plate
+		if notified, ok := s.notified[plate]; ok && notified.Equal(expires) {
+			continue
+		}
+		rec := rec
+		records = append(records, &rec)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].AccessExpiresAt.Before(records[j].AccessExpiresAt)
+	})
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	for _, rec := range records {
+		s.notified[rec.PlateNumber] = rec.AccessExpiresAt
+	}
+	return records, nil
+}
+
+func (s *MemoryStore) UpsertWatchlistEntry(ctx context.Context, e *models.WatchlistEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.watchlist[e.PlateNumber]; ok {
+		e.CreatedAt = existing.CreatedAt
+	} else {
+		e.CreatedAt = time.Now()
+	}
+	s.watchlist[e.PlateNumber] = *e
+	return nil
+}
+
+func (s *MemoryStore) GetWatchlistEntry(ctx context.Context, plateNumber string) (*models.WatchlistEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.watchlist[plateNumber]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &e, nil
+}
+
+func (s *MemoryStore) ListWatchlist(ctx context.Context) ([]models.WatchlistEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]models.WatchlistEntry, 0, len(s.watchlist))
+	for _, e := range s.watchlist {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].PlateNumber < entries[j].PlateNumber })
+	return entries, nil
+}
+
+func (s *MemoryStore) DeleteWatchlistEntry(ctx context.Context, plateNumber string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.watchlist[plateNumber]; !ok {
+		return ErrNotFound
+	}
+	delete(s.watchlist, plateNumber)
+	return nil
+}
+
+func (s *MemoryStore) SpillInboundEvent(ctx context.Context, channel, payload string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextSpillID++
+	s.spill = append(s.spill, models.SpilledEvent{
+		ID:        s.nextSpillID,
+		Channel:   channel,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	})
+	return nil
+}
+
+func (s *MemoryStore) TakeSpilledInboundEvents(ctx context.Context, limit int) ([]models.SpilledEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := min(limit, len(s.spill))
+	taken := append([]models.SpilledEvent(nil), s.spill[:n]...)
+	s.spill = s.spill[n:]
+	return taken, nil
+}
+
+func (s *MemoryStore) UpsertDeadLetter(ctx context.Context, dl *models.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for i := range s.deadLetters {
+		e := &s.deadLetters[i]
+		if e.EventID == dl.EventID && e.Handler == dl.Handler {
+			e.Error = dl.Error
+			e.Attempts++
+			e.LastFailedAt = now
+			*dl = *e
+			return nil
+		}
+	}
+
+	s.nextDeadID++
+	dl.ID = s.nextDeadID
+	dl.Attempts = 1
+	dl.FirstFailedAt = now
+	dl.LastFailedAt = now
+	s.deadLetters = append(s.deadLetters, *dl)
+	return nil
+}
+
+func (s *MemoryStore) ListDeadLetters(ctx context.Context, filter DeadLetterFilter) ([]models.DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	letters := make([]models.DeadLetter, 0)
+	for _, e := range s.deadLetters {
+		if filter.Channel != "" && e.Channel != filter.Channel {
+			continue
+		}
+		if filter.EventType != "" && e.EventType != filter.EventType {
+			continue
+		}
+		if filter.Handler != "" && e.Handler != filter.Handler {
+			continue
+		}
+		letters = append(letters, e)
+	}
+	sort.SliceStable(letters, func(i, j int) bool {
+		if !letters[i].LastFailedAt.Equal(letters[j].LastFailedAt) {
+			return letters[i].LastFailedAt.After(letters[j].LastFailedAt)
+		}
+		return letters[i].ID > letters[j].ID
+	})
+
+	if filter.Offset >= len(letters) {
+		return []models.DeadLetter{}, nil
+	}
+	letters = letters[filter.Offset:]
+	if filter.Limit > 0 && len(letters) > filter.Limit {
+		letters = letters[:filter.Limit]
+	}
+	return letters, nil
+}
+
+func (s *MemoryStore) GetDeadLetter(ctx context.Context, id int64) (*models.DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.deadLetters {
+		if e.ID == id {
+			return &e, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) DeleteDeadLetter(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, e := range s.deadLetters {
+		if e.ID == id {
+			s.deadLetters = slices.Delete(s.deadLetters, i, i+1)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *MemoryStore) InsertWebhookCredential(ctx context.Context, c *models.WebhookCredential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.credentials[c.ID]; ok {
+		return fmt.Errorf("webhook credential %s already exists", c.ID)
+	}
+	c.CreatedAt = time.Now()
+	c.Active = true
+	s.credentials[c.ID] = *c
+	return nil
+}
+
+func (s *MemoryStore) GetWebhookCredential(ctx context.Context, id string, withSecret bool) (*models.WebhookCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.credentials[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c.Active = credentialActive(c, time.Now())
+	if !withSecret {
+		c.Secret = ""
+	}
+	return &c, nil
+}
+
+func (s *MemoryStore) ListWebhookCredentials(ctx context.Context, filter WebhookCredentialFilter) ([]models.WebhookCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	creds := make([]models.WebhookCredential, 0)
+	for _, c := range s.credentials {
+		c.Active = credentialActive(c, now)
+		c.Secret = ""
+		if filter.Site != "" && c.Site != filter.Site {
+			continue
+		}
+		if filter.CameraID != "" && c.CameraID != filter.CameraID {
+			continue
+		}
+		if !filter.IncludeInactive && !c.Active {
+			continue
+		}
+		creds = append(creds, c)
+	}
+	sort.Slice(creds, func(i, j int) bool {
+		if !creds[i].CreatedAt.Equal(creds[j].CreatedAt) {
+			return creds[i].CreatedAt.After(creds[j].CreatedAt)
+		}
+		return creds[i].ID < creds[j].ID
+	})
+	return creds, nil
+}
+
+func (s *MemoryStore) RevokeWebhookCredential(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.credentials[id]
+	if !ok || c.RevokedAt != nil {
+		return ErrNotFound
+	}
+	revokedAt := time.Now()
+	c.RevokedAt = &revokedAt
+	s.credentials[id] = c
+	return nil
+}
+
+func (s *MemoryStore) ExpireWebhookCredential(ctx context.Context, id string, after time.Duration, rotatedTo string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	c, ok := s.credentials[id]
+	if !ok || !credentialActive(c, now) {
+		return ErrNotFound
+	}
+	if expires := now.Add(after); c.ExpiresAt == nil || expires.Before(*c.ExpiresAt) {
+		c.ExpiresAt = &expires
+	}
+	c.RotatedTo = rotatedTo
+	s.credentials[id] = c
+	return nil
+}
+
+func credentialActive(c models.WebhookCredential, now time.Time) bool {
+	return c.RevokedAt == nil && (c.ExpiresAt == nil || c.ExpiresAt.After(now))
+}
+
+func (s *MemoryStore) MarkEventProcessed(ctx context.Context, consumer, eventID string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := processedKey{consumer, eventID}
+	now := time.Now()
+	if expires, ok := s.processed[key]; ok && expires.After(now) {
+		return false, nil
+	}
+	s.processed[key] = now.Add(ttl)
+	return true, nil
+}
+
+func (s *MemoryStore) PurgeProcessedEvents(ctx context.Context, limit int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed int64
+	now := time.Now()
+	for key, expires := range s.processed {
+		if removed == int64(limit) {
+			break
+		}
+		if !expires.After(now) {
+			delete(s.processed, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (s *MemoryStore) InsertParkingEvent(ctx context.Context, e *models.ParkingEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextEventID++
+	e.ID = s.nextEventID
+	e.CreatedAt = time.Now()
+	if e.ReceivedAt.IsZero() {
+		e.ReceivedAt = e.CreatedAt
+	}
+	if e.EventTime.IsZero() {
+		e.EventTime = e.ReceivedAt
+	}
+	s.events = append(s.events, *e)
+	return nil
+}
+
+func (s *MemoryStore) ListParkingEvents(ctx context.Context, plateNumber string) ([]models.ParkingEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make([]models.ParkingEvent, 0)
+	for _, e := range s.events {
+		if e.PlateNumber == plateNumber {
+			events = append(events, e)
+		}
+	}
+	sortNewestEventFirst(events)
+	return events, nil
+}
+
+func (s *MemoryStore) LatestParkingEvent(ctx context.Context, plateNumber string, at time.Time) (*models.ParkingEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var latest *models.ParkingEvent
+	for i, e := range s.events {
+		if e.PlateNumber != plateNumber || (!at.IsZero() && e.EventTime.After(at)) {
+			continue
+		}
+		if latest == nil || newerParkingEvent(e, *latest) {
+			latest = &s.events[i]
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	event := *latest
+	return &event, nil
+}
+
+func (s *MemoryStore) ListParkedVehicles(ctx context.Context) ([]models.ParkingEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	latest := make(map[string]models.ParkingEvent)
+	for _, e := range s.events {
+		if l, ok := latest[e.PlateNumber]; !ok || newerParkingEvent(e, l) {
+			latest[e.PlateNumber] = e
+		}
+	}
+
+	parked := make([]models.ParkingEvent, 0)
+	for _, e := range latest {
+		if e.EventType == "entry" {
+			parked = append(parked, e)
+		}
+	}
+	sortNewestEventFirst(parked)
+	return parked, nil
+}
+
+// newerParkingEvent orders events by event time, then id.
+func newerParkingEvent(a, b models.ParkingEvent) bool {
+	if !a.EventTime.Equal(b.EventTime) {
+		return a.EventTime.After(b.EventTime)
+	}
+	return a.ID > b.ID
+}
+
+func sortNewestEventFirst(events []models.ParkingEvent) {
+	sort.Slice(events, func(i, j int) bool { return newerParkingEvent(events[i], events[j]) })
+}
+
+func (s *MemoryStore) SearchParkingEvents(ctx context.Context, filter ParkingEventFilter) ([]models.ParkingEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Events are appended with increasing ids, so s.events is in id order
+	events := make([]models.ParkingEvent, 0)
+	for _, e := range s.events {
+		if filter.Limit > 0 && len(events) == filter.Limit {
+			break
+		}
+		switch {
+		case e.ID <= filter.AfterID,
+			filter.PlateNumber != "" && e.PlateNumber != filter.PlateNumber,
+			filter.EventType != "" && e.EventType != filter.EventType,
+			filter.Location != "" && e.Location != filter.Location,
+			!filter.From.IsZero() && e.EventTime.Before(filter.From),
+			!filter.To.IsZero() && !e.EventTime.Before(filter.To):
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func (s *MemoryStore) InsertOutboxEvent(ctx context.Context, channel, payload string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.nextOutboxID++
+	s.outbox = append(s.outbox, models.OutboxEvent{
+		ID:            s.nextOutboxID,
+		Channel:       channel,
+		Payload:       payload,
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	})
+	return s.nextOutboxID, nil
+}
+
+func (s *MemoryStore) ListOutboxEvents(ctx context.Context, filter OutboxFilter) ([]models.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := []models.OutboxEvent{}
+	skipped := 0
+	for _, e := range s.outbox {
+		if filter.Limit > 0 && len(events) >= filter.Limit {
+			break
+		}
+		if filter.Status != "" && e.Status != filter.Status {
+			continue
+		}
+		if filter.Channel != "" && e.Channel != filter.Channel {
+			continue
+		}
+		if !filter.CreatedAfter.IsZero() && e.CreatedAt.Before(filter.CreatedAfter) {
+			continue
+		}
+		if !filter.CreatedBefore.IsZero() && e.CreatedAt.After(filter.CreatedBefore) {
+			continue
+		}
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func (s *MemoryStore) GetOutboxEvent(ctx context.Context, id int64) (*models.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.outbox {
+		if e.ID == id {
+			return &e, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) OutboxStats(ctx context.Context) (*models.OutboxStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	stats := &models.OutboxStats{Counts: map[string]int{}}
+	for _, e := range s.outbox {
+		stats.Counts[e.Status]++
+		if e.Status != models.OutboxStatusPending {
+			continue
+		}
+		stats.Pending++
+		if !e.NextAttemptAt.After(now) {
+			stats.Due++
+		}
+		if stats.OldestPendingAt == nil || e.CreatedAt.Before(*stats.OldestPendingAt) {
+			createdAt := e.CreatedAt
+			stats.OldestPendingAt = &createdAt
+		}
+	}
+	if stats.OldestPendingAt != nil {
+		stats.OldestPendingAgeSeconds = now.Sub(*stats.OldestPendingAt).Seconds()
+	}
+	return stats, nil
+}
+
+func (s *MemoryStore) ClaimOutboxEvents(ctx context.Context, owner string, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	until := now.Add(lease)
+	events := []models.OutboxEvent{}
+	for i := range s.outbox {
+		if len(events) >= limit {
+			break
+		}
+		e := &s.outbox[i]
+		if e.Status != models.OutboxStatusPending || e.NextAttemptAt.After(now) || (e.LockedUntil != nil && !e.LockedUntil.Before(now)) {
+			continue
+		}
+		e.LockedBy = owner
+		e.LockedUntil = &until
+		events = append(events, *e)
+	}
+	return events, nil
+}
+
+func (s *MemoryStore) MarkOutboxSent(ctx context.Context, id int64, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.outbox {
+		e := &s.outbox[i]
+		if e.ID != id {
+			continue
+		}
+		if e.Status != models.OutboxStatusPending || e.LockedBy != owner {
+			return ErrNotFound
+		}
+		now := time.Now()
+		e.Status = models.OutboxStatusSent
+		e.SentAt = &now
+		e.LockedBy = ""
+		e.LockedUntil = nil
+		return nil
+	}
+	return ErrNotFound
+}
+
+func (s *MemoryStore) IncrementOutboxAttempts(ctx context.Context, id int64, errMsg string, retryIn time.Duration, maxAttempts int) (bool, error) {
+	deadLettered := false
+	err := s.updateOutboxWhere(id, []string{models.OutboxStatusPending}, func(e *models.OutboxEvent) {
+		e.Attempts++
+		e.LastError = errMsg
+		e.NextAttemptAt = time.Now().Add(retryIn)
+		if e.Attempts >= maxAttempts {
+			e.Status = models.OutboxStatusFailed
+			deadLettered = true
+		}
+		e.LockedBy = ""
+		e.LockedUntil = nil
+	})
+	return deadLettered, err
+}
+
+func (s *MemoryStore) RequeueOutboxEvent(ctx context.Context, id int64) error {
+	statuses := []string{models.OutboxStatusPending, models.OutboxStatusFailed, models.OutboxStatusDiscarded}
+	return s.updateOutboxWhere(id, statuses, func(e *models.OutboxEvent) {
+		e.Status = models.OutboxStatusPending
+		e.Attempts = 0
+		e.NextAttemptAt = time.Now()
+		e.LockedBy = ""
+		e.LockedUntil = nil
+	})
+}
+
+func (s *MemoryStore) DiscardOutboxEvent(ctx context.Context, id int64) error {
+	statuses := []string{models.OutboxStatusPending, models.OutboxStatusFailed}
+	return s.updateOutboxWhere(id, statuses, func(e *models.OutboxEvent) {
+		e.Status = models.OutboxStatusDiscarded
+		e.LockedBy = ""
+		e.LockedUntil = nil
+	})
+}
+
+func (s *MemoryStore) PurgeSentOutboxEvents(ctx context.Context, olderThan time.Duration, limit int, archive bool) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-olderThan)
+	var removed int64
+	kept := s.outbox[:0]
+	for _, e := range s.outbox {
+		if removed < int64(limit) && e.Status == models.OutboxStatusSent && e.SentAt != nil && e.SentAt.Before(cutoff) {
+			removed++
+			if archive {
+				s.archive = append(s.archive, e)
+			}
+			continue
+		}
+		kept = append(kept, e)
+	}
+	s.outbox = kept
+	return removed, nil
+}
+
+// updateOutboxWhere applies fn to the outbox row with the given id if its
+// status is one of statuses (any status when nil), and returns ErrNotFound
+// otherwise.
+func (s *MemoryStore) updateOutboxWhere(id int64, statuses []string, fn func(e *models.OutboxEvent)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.outbox {
+		if s.outbox[i].ID != id {
+			continue
+		}
+		if statuses != nil && !slices.Contains(statuses, s.outbox[i].Status) {
+			return ErrNotFound
+		}
+		fn(&s.outbox[i])
+		return nil
+	}
+	return ErrNotFound
+}
+
+// OutboxEvents returns a copy of every outbox row, sent or not, in insertion
+// order. It is intended for assertions in tests.
+func (s *MemoryStore) OutboxEvents() []models.OutboxEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]models.OutboxEvent(nil), s.outbox...)
+}
+
+// parseFilterDate accepts the date formats the HTTP API documents for
+// date_from/date_to.
+func parseFilterDate(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
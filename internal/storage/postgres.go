@@ -0,0 +1,1007 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"licenseplate-plugin/internal/database"
+	"licenseplate-plugin/internal/models"
+)
+
+const outboxColumns = `id, channel, payload, status, attempts, last_error, next_attempt_at, created_at, sent_at, locked_by, locked_until`
+
+const plateColumns = `plate_number, guest_name, room_number, check_in, check_out, vehicle_make, vehicle_model, notes, visitor_type, access_expires_at, purpose, created_at`
+
+// PostgresStore implements Store on top of the shared connection pool.
+type PostgresStore struct {
+	db *database.Database
+	q  database.Executor
+}
+
+func NewPostgresStore(db *database.Database) *PostgresStore {
+	return &PostgresStore{
+		db: db,
+		q:  db,
+	}
+}
+
+func (s *PostgresStore) WithinTx(ctx context.Context, fn func(tx Store) error) error {
+	if _, inTx := s.q.(*database.Tx); inTx {
+		return fn(s)
+	}
+
+	return s.db.WithinTx(ctx, func(tx *database.Tx) error {
+		return fn(&PostgresStore{db: s.db, q: tx})
+	})
+}
+
+func (s *PostgresStore) UpsertPlate(ctx context.Context, rec *models.LicensePlateRecord) error {
+	query := `
+		INSERT INTO license_plates (plate_number, guest_name, room_number, check_in, vehicle_make, vehicle_model, notes, visitor_type, access_expires_at, purpose, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $4)
+		ON CONFLICT (plate_number)
+		DO UPDATE SET guest_name = $2, room_number = $3, check_in = $4, vehicle_make = $5, vehicle_model = $6, notes = $7, visitor_type = $8, access_expires_at = $9, purpose = $10, updated_at = NOW(),
+			access_expiry_notified_at = CASE
+				WHEN license_plates.access_expires_at IS DISTINCT FROM EXCLUDED.access_expires_at THEN NULL
+				ELSE license_plates.access_expiry_notified_at
+			END
+		RETURNING created_at
+	`
+
+	row := s.q.QueryRow(ctx, query, rec.PlateNumber, rec.GuestName, rec.RoomNumber, rec.CheckIn.UTC(), rec.VehicleMake, rec.VehicleModel, rec.Notes, rec.VisitorType, nullTime(rec.AccessExpiresAt), rec.Purpose)
+	return row.Scan(&rec.CreatedAt)
+}
+
+func (s *PostgresStore) CreatePlateIfAbsent(ctx context.Context, rec *models.LicensePlateRecord) (bool, error) {
+	query := `
+		INSERT INTO license_plates (plate_number, guest_name, check_in, notes, visitor_type, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (plate_number) DO NOTHING
+		RETURNING created_at
+	`
+
+	err := s.q.QueryRow(ctx, query, rec.PlateNumber, rec.GuestName, rec.CheckIn.UTC(), rec.Notes, rec.VisitorType).Scan(&rec.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *PostgresStore) GetPlate(ctx context.Context, plateNumber string) (*models.LicensePlateRecord, error) {
+	query := `SELECT ` + plateColumns + ` FROM license_plates WHERE plate_number = $1`
+
+	record, err := scanLicensePlateRecord(s.q.QueryRow(ctx, query, plateNumber))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return record, err
+}
+
+func (s *PostgresStore) ListPlates(ctx context.Context, filter PlateFilter) ([]*models.LicensePlateRecord, error) {
+	// Build dynamic query based on filters
+	query := `SELECT ` + plateColumns + ` FROM license_plates WHERE 1=1`
+
+	args := make([]interface{}, 0)
+	argIndex := 1
+
+	if filter.PlateNumber != "" {
+		query += fmt.Sprintf(" AND plate_number = $%d", argIndex)
+		args = append(args, filter.PlateNumber)
+		argIndex++
+	}
+
+	// Search in plate number or guest name
+	if filter.Search != "" {
+		query += fmt.Sprintf(" AND (UPPER(plate_number) LIKE $%d OR UPPER(guest_name) LIKE $%d)", argIndex, argIndex)
+		args = append(args, "%"+strings.ToUpper(filter.Search)+"%")
+		argIndex++
+	}
+
+	if filter.GuestName != "" {
+		query += fmt.Sprintf(" AND LOWER(guest_name) LIKE LOWER($%d)", argIndex)
+		args = append(args, "%"+filter.GuestName+"%")
+		argIndex++
+	}
+
+	if filter.VisitorType != "" {
+		query += fmt.Sprintf(" AND visitor_type = $%d", argIndex)
+		args = append(args, filter.VisitorType)
+		argIndex++
+	}
+
+	if filter.DateFrom != "" {
+		query += fmt.Sprintf(" AND check_in >= $%d", argIndex)
+		args = append(args, filter.DateFrom)
+		argIndex++
+	}
+
+	if filter.DateTo != "" {
+		query += fmt.Sprintf(" AND check_in <= $%d", argIndex)
+		args = append(args, filter.DateTo)
+		argIndex++
+	}
+
+	query += " ORDER BY created_at DESC"
+
+	rows, err := s.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*models.LicensePlateRecord, 0)
+	for rows.Next() {
+		record, err := scanLicensePlateRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (s *PostgresStore) SearchPlates(ctx context.Context, filter PlateRangeFilter) ([]*models.LicensePlateRecord, error) {
+	query := `SELECT ` + plateColumns + ` FROM license_plates WHERE 1=1`
+	args := []interface{}{}
+
+	if filter.PlateNumber != "" {
+		args = append(args, filter.PlateNumber)
+		query += fmt.Sprintf(" AND plate_number = $%d", len(args))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From.UTC())
+		query += fmt.Sprintf(" AND check_in >= $%d", len(args))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To.UTC())
+		query += fmt.Sprintf(" AND check_in < $%d", len(args))
+	}
+	if filter.AfterPlate != "" {
+		args = append(args, filter.AfterCheckIn.UTC(), filter.AfterPlate)
+		query += fmt.Sprintf(" AND (check_in, plate_number) > ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	query += " ORDER BY check_in, plate_number"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := s.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*models.LicensePlateRecord, 0)
+	for rows.Next() {
+		record, err := scanLicensePlateRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (s *PostgresStore) DeletePlate(ctx context.Context, plateNumber string) error {
+	return expectOneRow(s.q.Execute(ctx, `DELETE FROM license_plates WHERE plate_number = $1`, plateNumber))
+}
+
+func (s *PostgresStore) ClaimExpiredAccess(ctx context.Context, limit int) ([]*models.LicensePlateRecord, error) {
+	query := `
+		UPDATE license_plates SET access_expiry_notified_at = NOW()
+		WHERE plate_number IN (
+			SELECT plate_number FROM license_plates
+			WHERE access_expires_at <= NOW() AND access_expiry_notified_at IS NULL
+			ORDER BY access_expires_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + plateColumns
+
+	rows, err := s.q.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*models.LicensePlateRecord
+	for rows.Next() {
+		record, err := scanLicensePlateRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (s *PostgresStore) UpsertWatchlistEntry(ctx context.Context, e *models.WatchlistEntry) error {
+	query := `
+		INSERT INTO watchlist (plate_number, reason)
+		VALUES ($1, $2)
+		ON CONFLICT (plate_number) DO UPDATE SET reason = EXCLUDED.reason
+		RETURNING created_at
+	`
+	return s.q.QueryRow(ctx, query, e.PlateNumber, e.Reason).Scan(&e.CreatedAt)
+}
+
+func (s *PostgresStore) GetWatchlistEntry(ctx context.Context, plateNumber string) (*models.WatchlistEntry, error) {
+	var e models.WatchlistEntry
+	err := s.q.QueryRow(ctx, `SELECT plate_number, reason, created_at FROM watchlist WHERE plate_number = $1`, plateNumber).
+		Scan(&e.PlateNumber, &e.Reason, &e.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *PostgresStore) ListWatchlist(ctx context.Context) ([]models.WatchlistEntry, error) {
+	rows, err := s.q.Query(ctx, `SELECT plate_number, reason, created_at FROM watchlist ORDER BY plate_number`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.WatchlistEntry{}
+	for rows.Next() {
+		var e models.WatchlistEntry
+		if err := rows.Scan(&e.PlateNumber, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *PostgresStore) DeleteWatchlistEntry(ctx context.Context, plateNumber string) error {
+	return expectOneRow(s.q.Execute(ctx, `DELETE FROM watchlist WHERE plate_number = $1`, plateNumber))
+}
+
+func (s *PostgresStore) SpillInboundEvent(ctx context.Context, channel, payload string) error {
+	_, err := s.q.Execute(ctx, `INSERT INTO inbound_event_spill (channel, payload) VALUES ($1, $2)`, channel, payload)
+	return err
+}
+
+func (s *PostgresStore) TakeSpilledInboundEvents(ctx context.Context, limit int) ([]models.SpilledEvent, error) {
+	query := `
+		DELETE FROM inbound_event_spill
+		WHERE id IN (
+			SELECT id FROM inbound_event_spill
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, channel, payload, created_at
+	`
+
+	rows, err := s.q.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.SpilledEvent
+	for rows.Next() {
+		var e models.SpilledEvent
+		if err := rows.Scan(&e.ID, &e.Channel, &e.Payload, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not preserve the subquery's order
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+func (s *PostgresStore) UpsertDeadLetter(ctx context.Context, dl *models.DeadLetter) error {
+	query := `
+		INSERT INTO inbound_dead_letters (channel, event_id, event_type, handler, payload, error)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (event_id, handler) DO UPDATE
+		SET error = EXCLUDED.error,
+			attempts = inbound_dead_letters.attempts + 1,
+			last_failed_at = NOW()
+		RETURNING id, attempts, first_failed_at, last_failed_at
+	`
+
+	row := s.q.QueryRow(ctx, query, dl.Channel, dl.EventID, dl.EventType, dl.Handler, dl.Payload, dl.Error)
+	return row.Scan(&dl.ID, &dl.Attempts, &dl.FirstFailedAt, &dl.LastFailedAt)
+}
+
+const deadLetterColumns = `id, channel, event_id, event_type, handler, payload, error, attempts, first_failed_at, last_failed_at`
+
+func (s *PostgresStore) ListDeadLetters(ctx context.Context, filter DeadLetterFilter) ([]models.DeadLetter, error) {
+	query := `SELECT ` + deadLetterColumns + ` FROM inbound_dead_letters WHERE 1=1`
+	var args []interface{}
+
+	if filter.Channel != "" {
+		args = append(args, filter.Channel)
+		query += fmt.Sprintf(" AND channel = $%d", len(args))
+	}
+	if filter.EventType != "" {
+		args = append(args, filter.EventType)
+		query += fmt.Sprintf(" AND event_type = $%d", len(args))
+	}
+	if filter.Handler != "" {
+		args = append(args, filter.Handler)
+		query += fmt.Sprintf(" AND handler = $%d", len(args))
+	}
+
+	query += " ORDER BY last_failed_at DESC, id DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := s.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	letters := make([]models.DeadLetter, 0)
+	for rows.Next() {
+		dl, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, *dl)
+	}
+	return letters, rows.Err()
+}
+
+func (s *PostgresStore) GetDeadLetter(ctx context.Context, id int64) (*models.DeadLetter, error) {
+	query := `SELECT ` + deadLetterColumns + ` FROM inbound_dead_letters WHERE id = $1`
+
+	dl, err := scanDeadLetter(s.q.QueryRow(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return dl, err
+}
+
+func (s *PostgresStore) DeleteDeadLetter(ctx context.Context, id int64) error {
+	return expectOneRow(s.q.Execute(ctx, `DELETE FROM inbound_dead_letters WHERE id = $1`, id))
+}
+
+func scanDeadLetter(scanner rowScanner) (*models.DeadLetter, error) {
+	var dl models.DeadLetter
+	err := scanner.Scan(&dl.ID, &dl.Channel, &dl.EventID, &dl.EventType, &dl.Handler, &dl.Payload, &dl.Error, &dl.Attempts, &dl.FirstFailedAt, &dl.LastFailedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &dl, nil
+}
+
+// webhookCredentialColumns selects a credential without its secret;
+// active is evaluated against the database clock.
+const webhookCredentialColumns = `id, site, camera_id, description, created_at, expires_at, revoked_at, rotated_to,
+	(revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())) AS active`
+
+func (s *PostgresStore) InsertWebhookCredential(ctx context.Context, c *models.WebhookCredential) error {
+	query := `
+		INSERT INTO webhook_credentials (id, site, camera_id, secret, description)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+	if err := s.q.QueryRow(ctx, query, c.ID, c.Site, c.CameraID, c.Secret, c.Description).Scan(&c.CreatedAt); err != nil {
+		return err
+	}
+	c.Active = true
+	return nil
+}
+
+func (s *PostgresStore) GetWebhookCredential(ctx context.Context, id string, withSecret bool) (*models.WebhookCredential, error) {
+	query := `SELECT ` + webhookCredentialColumns + `, CASE WHEN $2 THEN secret ELSE '' END FROM webhook_credentials WHERE id = $1`
+
+	row := s.q.QueryRow(ctx, query, id, withSecret)
+	var c models.WebhookCredential
+	var expiresAt, revokedAt sql.NullTime
+	var rotatedTo sql.NullString
+	err := row.Scan(&c.ID, &c.Site, &c.CameraID, &c.Description, &c.CreatedAt, &expiresAt, &revokedAt, &rotatedTo, &c.Active, &c.Secret)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.ExpiresAt, c.RevokedAt, c.RotatedTo = timePtr(expiresAt), timePtr(revokedAt), rotatedTo.String
+	return &c, nil
+}
+
+func (s *PostgresStore) ListWebhookCredentials(ctx context.Context, filter WebhookCredentialFilter) ([]models.WebhookCredential, error) {
+	query := `SELECT ` + webhookCredentialColumns + ` FROM webhook_credentials WHERE 1=1`
+	var args []interface{}
+
+	if filter.Site != "" {
+		args = append(args, filter.Site)
+		query += fmt.Sprintf(" AND site = $%d", len(args))
+	}
+	if filter.CameraID != "" {
+		args = append(args, filter.CameraID)
+		query += fmt.Sprintf(" AND camera_id = $%d", len(args))
+	}
+	if !filter.IncludeInactive {
+		query += " AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())"
+	}
+	query += " ORDER BY created_at DESC, id"
+
+	rows, err := s.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	creds := make([]models.WebhookCredential, 0)
+	for rows.Next() {
+		var c models.WebhookCredential
+		var expiresAt, revokedAt sql.NullTime
+		var rotatedTo sql.NullString
+		if err := rows.Scan(&c.ID, &c.Site, &c.CameraID, &c.Description, &c.CreatedAt, &expiresAt, &revokedAt, &rotatedTo, &c.Active); err != nil {
+			return nil, err
+		}
+		c.ExpiresAt, c.RevokedAt, c.RotatedTo = timePtr(expiresAt), timePtr(revokedAt), rotatedTo.String
+		creds = append(creds, c)
+	}
+	return creds, rows.Err()
+}
+
+func (s *PostgresStore) RevokeWebhookCredential(ctx context.Context, id string) error {
+	query := `UPDATE webhook_credentials SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+	return expectOneRow(s.q.Execute(ctx, query, id))
+}
+
+func (s *PostgresStore) ExpireWebhookCredential(ctx context.Context, id string, after time.Duration, rotatedTo string) error {
+	// Never extend a key that already expires sooner
+	query := `
+		UPDATE webhook_credentials
+		SET expires_at = LEAST(COALESCE(expires_at, 'infinity'), NOW() + make_interval(secs => $2)),
+			rotated_to = $3
+		WHERE id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+	`
+	return expectOneRow(s.q.Execute(ctx, query, id, after.Seconds(), rotatedTo))
+}
+
+// parkingEventColumns is the column list read by scanParkingEvents
+const parkingEventColumns = `id, plate_number, event_type, event_time, received_at, clock_skew, out_of_order,
+	location, camera_id, confidence, notes, webhook_key_id, created_at`
+
+func (s *PostgresStore) InsertParkingEvent(ctx context.Context, e *models.ParkingEvent) error {
+	query := `
+		INSERT INTO parking_events (plate_number, event_type, event_time, received_at, clock_skew, out_of_order,
+			location, camera_id, confidence, notes, webhook_key_id)
+		VALUES ($1, $2, COALESCE($3, $4, NOW()), COALESCE($4, NOW()), $5, $6, $7, $8, $9, $10, NULLIF($11, ''))
+		RETURNING id, event_time, received_at, created_at
+	`
+
+	row := s.q.QueryRow(ctx, query, e.PlateNumber, e.EventType, nullTime(e.EventTime), nullTime(e.ReceivedAt), e.ClockSkew, e.OutOfOrder,
+		e.Location, e.CameraID, e.Confidence, e.Notes, e.WebhookKeyID)
+	return row.Scan(&e.ID, &e.EventTime, &e.ReceivedAt, &e.CreatedAt)
+}
+
+func (s *PostgresStore) ListParkingEvents(ctx context.Context, plateNumber string) ([]models.ParkingEvent, error) {
+	query := `
+		SELECT ` + parkingEventColumns + `
+		FROM parking_events
+		WHERE plate_number = $1
+		ORDER BY event_time DESC, id DESC
+	`
+
+	rows, err := s.q.Query(ctx, query, plateNumber)
+	if err != nil {
+		return nil, err
+	}
+	return scanParkingEvents(rows)
+}
+
+func (s *PostgresStore) LatestParkingEvent(ctx context.Context, plateNumber string, at time.Time) (*models.ParkingEvent, error) {
+	query := `
+		SELECT ` + parkingEventColumns + `
+		FROM parking_events
+		WHERE plate_number = $1 AND ($2::timestamp IS NULL OR event_time <= $2)
+		ORDER BY event_time DESC, id DESC
+		LIMIT 1
+	`
+
+	rows, err := s.q.Query(ctx, query, plateNumber, nullTime(at))
+	if err != nil {
+		return nil, err
+	}
+	events, err := scanParkingEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, ErrNotFound
+	}
+	return &events[0], nil
+}
+
+func (s *PostgresStore) ListParkedVehicles(ctx context.Context) ([]models.ParkingEvent, error) {
+	query := `
+		SELECT ` + parkingEventColumns + `
+		FROM (
+			SELECT DISTINCT ON (plate_number) *
+			FROM parking_events
+			ORDER BY plate_number, event_time DESC, id DESC
+		) latest
+		WHERE event_type = 'entry'
+		ORDER BY event_time DESC, id DESC
+	`
+
+	rows, err := s.q.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return scanParkingEvents(rows)
+}
+
+func (s *PostgresStore) SearchParkingEvents(ctx context.Context, filter ParkingEventFilter) ([]models.ParkingEvent, error) {
+	query := `
+		SELECT ` + parkingEventColumns + `
+		FROM parking_events
+		WHERE id > $1
+	`
+	args := []interface{}{filter.AfterID}
+
+	if filter.PlateNumber != "" {
+		args = append(args, filter.PlateNumber)
+		query += fmt.Sprintf(" AND plate_number = $%d", len(args))
+	}
+	if filter.EventType != "" {
+		args = append(args, filter.EventType)
+		query += fmt.Sprintf(" AND event_type = $%d", len(args))
+	}
+	if filter.Location != "" {
+		args = append(args, filter.Location)
+		query += fmt.Sprintf(" AND location = $%d", len(args))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From.UTC())
+		query += fmt.Sprintf(" AND event_time >= $%d", len(args))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To.UTC())
+		query += fmt.Sprintf(" AND event_time < $%d", len(args))
+	}
+
+	query += " ORDER BY id"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := s.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanParkingEvents(rows)
+}
+
+// scanParkingEvents reads parking_events rows selected with
+// parkingEventColumns and closes rows.
+func scanParkingEvents(rows *sql.Rows) ([]models.ParkingEvent, error) {
+	defer rows.Close()
+
+	events := make([]models.ParkingEvent, 0)
+	for rows.Next() {
+		var event models.ParkingEvent
+		var location, cameraID, notes, keyID sql.NullString
+		var confidence sql.NullFloat64
+
+		err := rows.Scan(
+			&event.ID,
+			&event.PlateNumber,
+			&event.EventType,
+			&event.EventTime,
+			&event.ReceivedAt,
+			&event.ClockSkew,
+			&event.OutOfOrder,
+			&location,
+			&cameraID,
+			&confidence,
+			&notes,
+			&keyID,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		event.Location = location.String
+		event.CameraID = cameraID.String
+		event.Confidence = confidence.Float64
+		event.Notes = notes.String
+		event.WebhookKeyID = keyID.String
+
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (s *PostgresStore) InsertOutboxEvent(ctx context.Context, channel, payload string) (int64, error) {
+	query := `
+		INSERT INTO outbox_events (channel, payload, attempts, created_at)
+		VALUES ($1, $2, 0, NOW())
+		RETURNING id
+	`
+
+	var id int64
+	err := s.q.QueryRow(ctx, query, channel, payload).Scan(&id)
+	return id, err
+}
+
+func (s *PostgresStore) ClaimOutboxEvents(ctx context.Context, owner string, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	// SKIP LOCKED lets concurrent claimers pass over rows another
+	// transaction is claiming right now; the lease keeps them off the row
+	// after that transaction commits until it is sent or the lease expires.
+	query := `
+		UPDATE outbox_events o
+		SET locked_by = $1, locked_until = NOW() + make_interval(secs => $3)
+		FROM (
+			SELECT id
+			FROM outbox_events
+			WHERE status = 'pending'
+			  AND next_attempt_at <= NOW()
+			  AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY created_at ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		) claimable
+		WHERE o.id = claimable.id
+		RETURNING o.id, o.channel, o.payload, o.status, o.attempts, o.last_error, o.next_attempt_at, o.created_at, o.sent_at, o.locked_by, o.locked_until
+	`
+
+	rows, err := s.q.Query(ctx, query, owner, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		e, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not preserve the subquery order
+	sort.Slice(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+	return events, nil
+}
+
+func (s *PostgresStore) MarkOutboxSent(ctx context.Context, id int64, owner string) error {
+	query := `
+		UPDATE outbox_events
+		SET status = 'sent', sent_at = NOW(), locked_by = NULL, locked_until = NULL
+		WHERE id = $1 AND status = 'pending' AND locked_by = $2
+	`
+	return expectOneRow(s.q.Execute(ctx, query, id, owner))
+}
+
+func (s *PostgresStore) IncrementOutboxAttempts(ctx context.Context, id int64, errMsg string, retryIn time.Duration, maxAttempts int) (bool, error) {
+	query := `
+		UPDATE outbox_events
+		SET attempts = attempts + 1,
+		    last_error = $2,
+		    next_attempt_at = NOW() + make_interval(secs => $3),
+		    status = CASE WHEN attempts + 1 >= $4 THEN 'failed' ELSE 'pending' END,
+		    locked_by = NULL,
+		    locked_until = NULL
+		WHERE id = $1 AND status = 'pending'
+		RETURNING status
+	`
+
+	var status string
+	err := s.q.QueryRow(ctx, query, id, errMsg, retryIn.Seconds(), maxAttempts).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+	return status == models.OutboxStatusFailed, nil
+}
+
+func (s *PostgresStore) ListOutboxEvents(ctx context.Context, filter OutboxFilter) ([]models.OutboxEvent, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbox_events WHERE 1=1`
+
+	args := make([]interface{}, 0)
+	argIndex := 1
+
+	if filter.Status != "" {
+		query += fmt.Sprintf(" AND status = $%d", argIndex)
+		args = append(args, filter.Status)
+		argIndex++
+	}
+
+	if filter.Channel != "" {
+		query += fmt.Sprintf(" AND channel = $%d", argIndex)
+		args = append(args, filter.Channel)
+		argIndex++
+	}
+
+	if !filter.CreatedAfter.IsZero() {
+		query += fmt.Sprintf(" AND created_at >= $%d", argIndex)
+		args = append(args, filter.CreatedAfter)
+		argIndex++
+	}
+
+	if !filter.CreatedBefore.IsZero() {
+		query += fmt.Sprintf(" AND created_at <= $%d", argIndex)
+		args = append(args, filter.CreatedBefore)
+		argIndex++
+	}
+
+	query += " ORDER BY created_at ASC, id ASC"
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argIndex)
+		args = append(args, filter.Limit)
+		argIndex++
+	}
+
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argIndex)
+		args = append(args, filter.Offset)
+		argIndex++
+	}
+
+	rows, err := s.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		e, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	return events, rows.Err()
+}
+
+func (s *PostgresStore) GetOutboxEvent(ctx context.Context, id int64) (*models.OutboxEvent, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbox_events WHERE id = $1`
+
+	e, err := scanOutboxEvent(s.q.QueryRow(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return e, err
+}
+
+func (s *PostgresStore) OutboxStats(ctx context.Context) (*models.OutboxStats, error) {
+	rows, err := s.q.Query(ctx, `SELECT status, COUNT(*) FROM outbox_events GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := &models.OutboxStats{Counts: map[string]int{}}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		stats.Counts[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	stats.Pending = stats.Counts[models.OutboxStatusPending]
+
+	// Compute the age in SQL so it is measured against the database clock
+	query := `
+		SELECT COUNT(*) FILTER (WHERE next_attempt_at <= NOW()),
+		       MIN(created_at),
+		       COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(created_at)), 0)
+		FROM outbox_events
+		WHERE status = 'pending'
+	`
+	var oldest sql.NullTime
+	err = s.q.QueryRow(ctx, query).Scan(&stats.Due, &oldest, &stats.OldestPendingAgeSeconds)
+	if err != nil {
+		return nil, err
+	}
+	stats.OldestPendingAt = timePtr(oldest)
+	return stats, nil
+}
+
+func (s *PostgresStore) RequeueOutboxEvent(ctx context.Context, id int64) error {
+	query := `
+		UPDATE outbox_events
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), locked_by = NULL, locked_until = NULL
+		WHERE id = $1 AND status <> 'sent'
+	`
+	return expectOneRow(s.q.Execute(ctx, query, id))
+}
+
+func (s *PostgresStore) DiscardOutboxEvent(ctx context.Context, id int64) error {
+	query := `
+		UPDATE outbox_events
+		SET status = 'discarded', locked_by = NULL, locked_until = NULL
+		WHERE id = $1 AND status IN ('pending', 'failed')
+	`
+	return expectOneRow(s.q.Execute(ctx, query, id))
+}
+
+func (s *PostgresStore) PurgeSentOutboxEvents(ctx context.Context, olderThan time.Duration, limit int, archive bool) (int64, error) {
+	// Lock a bounded batch with SKIP LOCKED so the purge never waits on,
+	// or holds up, the publisher
+	doomed := `
+		WITH doomed AS (
+			SELECT id
+			FROM outbox_events
+			WHERE status = 'sent' AND sent_at < NOW() - make_interval(secs => $1)
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+
+	if !archive {
+		query := doomed + `
+			DELETE FROM outbox_events o USING doomed WHERE o.id = doomed.id
+		`
+		return s.q.Execute(ctx, query, olderThan.Seconds(), limit)
+	}
+
+	// Count the deleted rows rather than the inserted ones: a row already in
+	// the archive is skipped by ON CONFLICT but still removed from the outbox
+	query := doomed + `,
+		moved AS (
+			DELETE FROM outbox_events o USING doomed WHERE o.id = doomed.id
+			RETURNING o.id, o.channel, o.payload, o.attempts, o.created_at, o.sent_at
+		),
+		archived AS (
+			INSERT INTO outbox_events_archive (id, channel, payload, attempts, created_at, sent_at, archived_at)
+			SELECT id, channel, payload, attempts, created_at, sent_at, NOW() FROM moved
+			ON CONFLICT (id) DO NOTHING
+		)
+		SELECT COUNT(*) FROM moved
+	`
+	var removed int64
+	err := s.q.QueryRow(ctx, query, olderThan.Seconds(), limit).Scan(&removed)
+	return removed, err
+}
+
+func (s *PostgresStore) MarkEventProcessed(ctx context.Context, consumer, eventID string, ttl time.Duration) (bool, error) {
+	// A concurrent insert of the same key waits for the other transaction,
+	// then either conflicts (duplicate) or succeeds if it rolled back
+	query := `
+		INSERT INTO processed_events (consumer, event_id, expires_at)
+		VALUES ($1, $2, NOW() + make_interval(secs => $3))
+		ON CONFLICT (consumer, event_id) DO UPDATE
+		SET processed_at = NOW(), expires_at = EXCLUDED.expires_at
+		WHERE processed_events.expires_at <= NOW()
+	`
+	n, err := s.q.Execute(ctx, query, consumer, eventID, ttl.Seconds())
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *PostgresStore) PurgeProcessedEvents(ctx context.Context, limit int) (int64, error) {
+	query := `
+		DELETE FROM processed_events
+		WHERE (consumer, event_id) IN (
+			SELECT consumer, event_id FROM processed_events
+			WHERE expires_at <= NOW()
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+	return s.q.Execute(ctx, query, limit)
+}
+
+// expectOneRow maps an UPDATE that matched nothing to ErrNotFound.
+func expectOneRow(rowsAffected int64, err error) error {
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanLicensePlateRecord scans a row selected with plateColumns.
+func scanLicensePlateRecord(scanner rowScanner) (*models.LicensePlateRecord, error) {
+	record := &models.LicensePlateRecord{}
+	var checkOut, expiresAt sql.NullTime
+	var roomNumber, vehicleMake, vehicleModel, notes, purpose sql.NullString
+
+	err := scanner.Scan(
+		&record.PlateNumber,
+		&record.GuestName,
+		&roomNumber,
+		&record.CheckIn,
+		&checkOut,
+		&vehicleMake,
+		&vehicleModel,
+		&notes,
+		&record.VisitorType,
+		&expiresAt,
+		&purpose,
+		&record.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle nullable fields
+	record.CheckOut = checkOut.Time
+	record.RoomNumber = roomNumber.String
+	record.VehicleMake = vehicleMake.String
+	record.VehicleModel = vehicleModel.String
+	record.Notes = notes.String
+	record.AccessExpiresAt = expiresAt.Time
+	record.Purpose = purpose.String
+
+	return record, nil
+}
+
+func scanOutboxEvent(scanner rowScanner) (*models.OutboxEvent, error) {
+	var e models.OutboxEvent
+	var lastError, lockedBy sql.NullString
+	var sentAt, lockedUntil sql.NullTime
+	if err := scanner.Scan(&e.ID, &e.Channel, &e.Payload, &e.Status, &e.Attempts, &lastError, &e.NextAttemptAt, &e.CreatedAt, &sentAt, &lockedBy, &lockedUntil); err != nil {
+		return nil, err
+	}
+
+	e.LastError = lastError.String
+	e.LockedBy = lockedBy.String
+	e.SentAt = timePtr(sentAt)
+	e.LockedUntil = timePtr(lockedUntil)
+	return &e, nil
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
+
+// nullTime maps the zero time to SQL NULL and converts other times to UTC,
+// since TIMESTAMP columns drop the offset rather than applying it.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
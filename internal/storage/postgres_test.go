@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"licenseplate-plugin/internal/database"
+	"licenseplate-plugin/internal/models"
+	"licenseplate-plugin/migrations"
+)
+
+// newTestPostgresStore connects to TEST_DATABASE_URL and applies the
+// migrations, or skips the test when it is not set.
+func newTestPostgresStore(t *testing.T) *PostgresStore {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := database.NewDatabase(url, database.Options{})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return NewPostgresStore(db)
+}
+
+func TestPostgresStoresTimesInUTC(t *testing.T) {
+	ctx := context.Background()
+	store := newTestPostgresStore(t)
+	plate := "UTCTEST" + time.Now().Format("150405")
+	t.Cleanup(func() {
+		store.q.Execute(ctx, `DELETE FROM parking_events WHERE plate_number = $1`, plate)
+		store.q.Execute(ctx, `DELETE FROM license_plates WHERE plate_number = $1`, plate)
+	})
+
+	plus2 := time.FixedZone("+02:00", 2*60*60)
+	at := time.Now().Add(-time.Hour).Truncate(time.Second).In(plus2)
+	e := &models.ParkingEvent{PlateNumber: plate, EventType: "entry", EventTime: at, ReceivedAt: at.Add(time.Second), Location: "Gate A", CameraID: "CAM-1"}
+	if err := store.InsertParkingEvent(ctx, e); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+	if err := store.UpsertPlate(ctx, &models.LicensePlateRecord{
+		PlateNumber: plate, GuestName: "Erin", VisitorType: "guest", CheckIn: at, AccessExpiresAt: at.Add(time.Hour),
+	}); err != nil {
+		t.Fatalf("upsert plate: %v", err)
+	}
+
+	// The stored wall clock is the UTC one, not the camera's
+	var eventTime, receivedAt, checkIn, expiresAt string
+	err := store.q.QueryRow(ctx, `
+		SELECT to_char(e.event_time, 'YYYY-MM-DD HH24:MI:SS'), to_char(e.received_at, 'YYYY-MM-DD HH24:MI:SS'),
+			to_char(p.check_in, 'YYYY-MM-DD HH24:MI:SS'), to_char(p.access_expires_at, 'YYYY-MM-DD HH24:MI:SS')
+		FROM parking_events e JOIN license_plates p USING (plate_number)
+		WHERE e.id = $1`, e.ID).Scan(&eventTime, &receivedAt, &checkIn, &expiresAt)
+	if err != nil {
+		t.Fatalf("read stored times: %v", err)
+	}
+	const layout = "2006-01-02 15:04:05"
+	want := map[string][2]string{
+		"event_time":        {eventTime, at.UTC().Format(layout)},
+		"received_at":       {receivedAt, at.Add(time.Second).UTC().Format(layout)},
+		"check_in":          {checkIn, at.UTC().Format(layout)},
+		"access_expires_at": {expiresAt, at.Add(time.Hour).UTC().Format(layout)},
+	}
+	for column, v := range want {
+		if v[0] != v[1] {
+			t.Errorf("%s stored as %s, want %s", column, v[0], v[1])
+		}
+	}
+
+	// Ranges given with an offset select the same instant
+	found, err := store.SearchParkingEvents(ctx, ParkingEventFilter{PlateNumber: plate, From: at, To: at.Add(time.Second)})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(found) != 1 || !found[0].EventTime.Equal(at) {
+		t.Fatalf("search from %s found %+v, want the event at that instant", at, found)
+	}
+	found, err = store.SearchParkingEvents(ctx, ParkingEventFilter{PlateNumber: plate, From: at.Add(time.Second)})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(found) != 0 {
+		t.Fatalf("search after the event found %+v", found)
+	}
+}
+
+func TestPostgresSearchPlatesPagesByCheckIn(t *testing.T) {
+	ctx := context.Background()
+	store := newTestPostgresStore(t)
+	prefix := "PG" + time.Now().Format("150405")
+	t.Cleanup(func() {
+		store.q.Execute(ctx, `DELETE FROM license_plates WHERE plate_number LIKE $1`, prefix+"%")
+	})
+
+	// Two plates per check-in time, so a page can end between them
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	for i := 0; i < 10; i++ {
+		rec := &models.LicensePlateRecord{
+			PlateNumber: fmt.Sprintf("%s%02d", prefix, i),
+			GuestName:   "Guest",
+			VisitorType: "guest",
+			CheckIn:     base.Add(time.Duration(i/2) * time.Minute),
+		}
+		if err := store.UpsertPlate(ctx, rec); err != nil {
+			t.Fatalf("upsert %s: %v", rec.PlateNumber, err)
+		}
+	}
+
+	// Minutes 1 to 3, given at +02:00: plates 2 to 7
+	plus2 := time.FixedZone("+02:00", 2*60*60)
+	filter := PlateRangeFilter{From: base.Add(time.Minute).In(plus2), To: base.Add(4 * time.Minute).In(plus2), Limit: 4}
+	var got []string
+	for {
+		records, err := store.SearchPlates(ctx, filter)
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if len(records) == 0 {
+			break
+		}
+		for _, r := range records {
+			if strings.HasPrefix(r.PlateNumber, prefix) {
+				got = append(got, r.PlateNumber[len(prefix):])
+			}
+		}
+		last := records[len(records)-1]
+		filter.AfterCheckIn, filter.AfterPlate = last.CheckIn, last.PlateNumber
+	}
+	if want := []string{"02", "03", "04", "05", "06", "07"}; !slices.Equal(got, want) {
+		t.Fatalf("paged plates = %v, want %v", got, want)
+	}
+}
+
+func TestLatestParkingEvent(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		testLatestParkingEvent(t, NewMemoryStore())
+	})
+	t.Run("postgres", func(t *testing.T) {
+		testLatestParkingEvent(t, newTestPostgresStore(t))
+	})
+}
+
+func testLatestParkingEvent(t *testing.T, store Store) {
+	ctx := context.Background()
+	plate := "LATEST" + time.Now().Format("150405.000000")
+	if pg, ok := store.(*PostgresStore); ok {
+		t.Cleanup(func() {
+			pg.q.Execute(ctx, `DELETE FROM parking_events WHERE plate_number = $1`, plate)
+		})
+	}
+
+	if _, err := store.LatestParkingEvent(ctx, plate, time.Time{}); err != ErrNotFound {
+		t.Fatalf("latest of a plate without events: err = %v, want %v", err, ErrNotFound)
+	}
+
+	// Inserted out of event-time order, with two events at the same time
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	insert := func(eventType string, at time.Time) int {
+		t.Helper()
+		e := &models.ParkingEvent{PlateNumber: plate, EventType: eventType, EventTime: at}
+		if err := store.InsertParkingEvent(ctx, e); err != nil {
+			t.Fatalf("insert %s at %s: %v", eventType, at, err)
+		}
+		return e.ID
+	}
+	exit := insert("exit", base.Add(30*time.Minute))
+	insert("entry", base)
+	sameTime := insert("exit", base)
+	late := insert("entry", base.Add(time.Hour))
+
+	plus2 := time.FixedZone("+02:00", 2*60*60)
+	tests := []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{"any time", time.Time{}, late},
+		{"at an event", base.Add(30 * time.Minute).In(plus2), exit},
+		{"between events", base.Add(45 * time.Minute), exit},
+		{"equal times by id", base.Add(time.Minute), sameTime},
+	}
+	for _, tt := range tests {
+		got, err := store.LatestParkingEvent(ctx, plate, tt.at)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got.ID != tt.want {
+			t.Errorf("%s: latest = %d (%s at %s), want %d", tt.name, got.ID, got.EventType, got.EventTime, tt.want)
+		}
+	}
+
+	if _, err := store.LatestParkingEvent(ctx, plate, base.Add(-time.Second)); err != ErrNotFound {
+		t.Fatalf("latest before every event: err = %v, want %v", err, ErrNotFound)
+	}
+}
+
+// TestPostgresArchivePurgeCountsDeletedRows checks that archiving reports
+// the rows removed from the outbox, including one already archived.
+func TestPostgresArchivePurgeCountsDeletedRows(t *testing.T) {
+	ctx := context.Background()
+	store := newTestPostgresStore(t)
+	channel := fmt.Sprintf("test-archive-%d", time.Now().UnixNano())
+
+	var ids []int64
+	for i := 0; i < 2; i++ {
+		id, err := store.InsertOutboxEvent(ctx, channel, fmt.Sprintf("event-%d", i))
+		if err != nil {
+			t.Fatalf("insert outbox event: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	t.Cleanup(func() {
+		store.q.Execute(ctx, `DELETE FROM outbox_events WHERE channel = $1`, channel)
+		store.q.Execute(ctx, `DELETE FROM outbox_events_archive WHERE channel = $1`, channel)
+	})
+	if _, err := store.q.Execute(ctx, `UPDATE outbox_events SET status = 'sent', sent_at = NOW() - INTERVAL '2 days' WHERE channel = $1`, channel); err != nil {
+		t.Fatalf("mark sent: %v", err)
+	}
+	// A previous run archived the first row but did not delete it
+	if _, err := store.q.Execute(ctx, `
+		INSERT INTO outbox_events_archive (id, channel, payload, attempts, created_at, sent_at)
+		SELECT id, channel, payload, attempts, created_at, sent_at FROM outbox_events WHERE id = $1
+	`, ids[0]); err != nil {
+		t.Fatalf("pre-archive: %v", err)
+	}
+
+	// Other tests' rows may be purged too, so only a lower bound is exact
+	removed, err := store.PurgeSentOutboxEvents(ctx, 24*time.Hour, 1000, true)
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if removed < 2 {
+		t.Fatalf("purge removed %d rows, want at least 2", removed)
+	}
+	var left int
+	if err := store.q.QueryRow(ctx, `SELECT COUNT(*) FROM outbox_events WHERE channel = $1`, channel).Scan(&left); err != nil {
+		t.Fatalf("count outbox: %v", err)
+	}
+	if left != 0 {
+		t.Fatalf("%d rows left in the outbox, want 0", left)
+	}
+}
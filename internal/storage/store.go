@@ -0,0 +1,222 @@
+// Package storage defines the persistence boundary used by the service layer,
+// with a Postgres implementation for production and an in-memory one for tests.
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"licenseplate-plugin/internal/models"
+)
+
+// ErrNotFound is returned when the requested row does not exist.
+var ErrNotFound = errors.New("not found")
+
+// PlateFilter narrows ListPlates. Empty fields are ignored.
+type PlateFilter struct {
+	PlateNumber string // Exact plate_number
+	Search      string // Case-insensitive match on plate_number or guest_name
+	GuestName   string // Case-insensitive match on guest_name only
+	VisitorType string // Exact visitor type
+	DateFrom    string // check_in >= date
+	DateTo      string // check_in <= date
+}
+
+// PlateRangeFilter narrows SearchPlates. Zero fields are ignored.
+type PlateRangeFilter struct {
+	PlateNumber  string
+	From         time.Time // check_in >= From
+	To           time.Time // check_in < To
+	AfterCheckIn time.Time // With AfterPlate, only plates after this
+	AfterPlate   string    // (check_in, plate_number) position
+	Limit        int
+}
+
+// Store is the full storage interface used by LicensePlateService.
+type Store interface {
+	PlateStore
+	ParkingEventStore
+	WatchlistStore
+	OutboxStore
+	InboundStore
+	LedgerStore
+	WebhookCredentialStore
+
+	// WithinTx runs fn against a Store bound to a single transaction. The
+	// transaction commits when fn returns nil and rolls back otherwise.
+	// Calling WithinTx on a transactional Store reuses the same transaction.
+	WithinTx(ctx context.Context, fn func(tx Store) error) error
+}
+
+// PlateStore persists registered license plates.
+type PlateStore interface {
+	// UpsertPlate inserts or updates a plate by plate number and sets
+	// rec.CreatedAt to the stored creation time.
+	UpsertPlate(ctx context.Context, rec *models.LicensePlateRecord) error
+	// CreatePlateIfAbsent inserts rec unless the plate already exists and
+	// reports whether a row was created.
+	CreatePlateIfAbsent(ctx context.Context, rec *models.LicensePlateRecord) (bool, error)
+	GetPlate(ctx context.Context, plateNumber string) (*models.LicensePlateRecord, error)
+	ListPlates(ctx context.Context, filter PlateFilter) ([]*models.LicensePlateRecord, error)
+	// SearchPlates returns plates matching filter ordered by check_in, then
+	// plate_number, so large ranges can be paged with AfterCheckIn and
+	// AfterPlate.
+	SearchPlates(ctx context.Context, filter PlateRangeFilter) ([]*models.LicensePlateRecord, error)
+	DeletePlate(ctx context.Context, plateNumber string) error
+	// ClaimExpiredAccess marks up to limit plates whose access_expires_at
+	// has passed, and was not announced yet, as notified and returns them.
+	// Changing a plate's access_expires_at makes it eligible again.
+	ClaimExpiredAccess(ctx context.Context, limit int) ([]*models.LicensePlateRecord, error)
+}
+
+// ParkingEventStore persists the entry/exit history of vehicles.
+type ParkingEventStore interface {
+	// InsertParkingEvent stores e and fills in its ID and CreatedAt. A zero
+	// ReceivedAt is replaced by the current time, and a zero EventTime by
+	// ReceivedAt.
+	InsertParkingEvent(ctx context.Context, e *models.ParkingEvent) error
+	// ListParkingEvents returns the events for a plate, newest event time
+	// first; events with equal times are ordered newest id first.
+	ListParkingEvents(ctx context.Context, plateNumber string) ([]models.ParkingEvent, error)
+	// LatestParkingEvent returns the plate's newest event by event time,
+	// then id, among those at or before at (any time when at is zero). It
+	// returns ErrNotFound if there is none.
+	LatestParkingEvent(ctx context.Context, plateNumber string, at time.Time) (*models.ParkingEvent, error)
+	// ListParkedVehicles returns, for each plate whose latest event by
+	// event time is an entry, that entry, newest first.
+	ListParkedVehicles(ctx context.Context) ([]models.ParkingEvent, error)
+	// SearchParkingEvents returns events matching filter ordered by id, so
+	// large ranges can be paged with AfterID.
+	SearchParkingEvents(ctx context.Context, filter ParkingEventFilter) ([]models.ParkingEvent, error)
+}
+
+// ParkingEventFilter narrows SearchParkingEvents. Zero fields are ignored.
+type ParkingEventFilter struct {
+	PlateNumber string
+	EventType   string    // entry or exit
+	Location    string    // Exact location
+	From        time.Time // event_time >= From
+	To          time.Time // event_time < To
+	AfterID     int       // Only events with a larger id
+	Limit       int
+}
+
+// WatchlistStore persists plates that raise watchlist.hit when detected.
+type WatchlistStore interface {
+	// UpsertWatchlistEntry adds or updates an entry and sets e.CreatedAt to
+	// the stored creation time.
+	UpsertWatchlistEntry(ctx context.Context, e *models.WatchlistEntry) error
+	GetWatchlistEntry(ctx context.Context, plateNumber string) (*models.WatchlistEntry, error)
+	ListWatchlist(ctx context.Context) ([]models.WatchlistEntry, error)
+	DeleteWatchlistEntry(ctx context.Context, plateNumber string) error
+}
+
+// OutboxFilter narrows ListOutboxEvents. Empty fields are ignored.
+type OutboxFilter struct {
+	Status        string
+	Channel       string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Limit         int
+	Offset        int
+}
+
+// OutboxStore persists events waiting to be published on the event bus.
+type OutboxStore interface {
+	InsertOutboxEvent(ctx context.Context, channel, payload string) (int64, error)
+	// ListOutboxEvents returns events matching filter, oldest first.
+	ListOutboxEvents(ctx context.Context, filter OutboxFilter) ([]models.OutboxEvent, error)
+	GetOutboxEvent(ctx context.Context, id int64) (*models.OutboxEvent, error)
+	OutboxStats(ctx context.Context) (*models.OutboxStats, error)
+	// ClaimOutboxEvents leases up to limit pending events that are due to
+	// owner for the given duration. Rows leased by another owner are skipped
+	// until their lease expires, so concurrent publishers never receive the
+	// same row.
+	ClaimOutboxEvents(ctx context.Context, owner string, limit int, lease time.Duration) ([]models.OutboxEvent, error)
+	// MarkOutboxSent sets sent_at and releases the lease. It returns
+	// ErrNotFound, without changing the row, unless the row is pending and
+	// leased to owner; another publisher or an admin may have taken it over
+	// after the lease expired.
+	MarkOutboxSent(ctx context.Context, id int64, owner string) error
+	// IncrementOutboxAttempts records a failed attempt, schedules the next
+	// one retryIn from now and releases the lease. Once attempts reaches
+	// maxAttempts the row is dead-lettered and it reports true.
+	IncrementOutboxAttempts(ctx context.Context, id int64, errMsg string, retryIn time.Duration, maxAttempts int) (bool, error)
+	// RequeueOutboxEvent resets a failed, discarded or pending event so it
+	// is published on the next tick. Sent events return ErrNotFound.
+	RequeueOutboxEvent(ctx context.Context, id int64) error
+	// DiscardOutboxEvent marks a failed or pending event as discarded.
+	DiscardOutboxEvent(ctx context.Context, id int64) error
+	// PurgeSentOutboxEvents removes up to limit events that were sent more
+	// than olderThan ago, copying them to outbox_events_archive first when
+	// archive is set. It returns the number of rows removed.
+	PurgeSentOutboxEvents(ctx context.Context, olderThan time.Duration, limit int, archive bool) (int64, error)
+}
+
+// InboundStore persists inbound bus messages the dispatcher could not
+// handle right away.
+type InboundStore interface {
+	SpillInboundEvent(ctx context.Context, channel, payload string) error
+	// TakeSpilledInboundEvents removes and returns up to limit spilled
+	// messages, oldest first. Concurrent callers receive disjoint rows.
+	TakeSpilledInboundEvents(ctx context.Context, limit int) ([]models.SpilledEvent, error)
+
+	// UpsertDeadLetter stores a failed message. A failure of the same event
+	// id in the same handler updates the existing row, replacing its error
+	// and incrementing attempts. It fills in dl's ID, Attempts and times.
+	UpsertDeadLetter(ctx context.Context, dl *models.DeadLetter) error
+	// ListDeadLetters returns dead letters matching filter, most recently
+	// failed first.
+	ListDeadLetters(ctx context.Context, filter DeadLetterFilter) ([]models.DeadLetter, error)
+	GetDeadLetter(ctx context.Context, id int64) (*models.DeadLetter, error)
+	DeleteDeadLetter(ctx context.Context, id int64) error
+}
+
+// DeadLetterFilter narrows ListDeadLetters. Empty fields are ignored.
+type DeadLetterFilter struct {
+	Channel   string
+	EventType string
+	Handler   string
+	Limit     int
+	Offset    int
+}
+
+// LedgerStore records which events each consumer has processed, so that
+// redelivered events can be recognised and skipped.
+type LedgerStore interface {
+	// MarkEventProcessed records eventID as processed by consumer until ttl
+	// from now. It reports false if the event was already recorded and has
+	// not expired, in which case nothing changes.
+	MarkEventProcessed(ctx context.Context, consumer, eventID string, ttl time.Duration) (bool, error)
+	// PurgeProcessedEvents removes up to limit expired ledger rows and
+	// returns the number removed.
+	PurgeProcessedEvents(ctx context.Context, limit int) (int64, error)
+}
+
+// WebhookCredentialFilter narrows ListWebhookCredentials. Empty fields are
+// ignored.
+type WebhookCredentialFilter struct {
+	Site            string
+	CameraID        string
+	IncludeInactive bool // Also list revoked and expired keys
+}
+
+// WebhookCredentialStore persists webhook signing keys. Whether a key is
+// active is decided by the store's clock.
+type WebhookCredentialStore interface {
+	// InsertWebhookCredential stores c and sets its CreatedAt.
+	InsertWebhookCredential(ctx context.Context, c *models.WebhookCredential) error
+	// GetWebhookCredential returns a key, including its secret only when
+	// withSecret is set.
+	GetWebhookCredential(ctx context.Context, id string, withSecret bool) (*models.WebhookCredential, error)
+	// ListWebhookCredentials returns keys without their secrets, newest first.
+	ListWebhookCredentials(ctx context.Context, filter WebhookCredentialFilter) ([]models.WebhookCredential, error)
+	// RevokeWebhookCredential disables a key immediately. Revoked or
+	// unknown keys return ErrNotFound.
+	RevokeWebhookCredential(ctx context.Context, id string) error
+	// ExpireWebhookCredential lets an active key expire after the given
+	// duration and records the key that replaces it. Inactive or unknown
+	// keys return ErrNotFound.
+	ExpireWebhookCredential(ctx context.Context, id string, after time.Duration, rotatedTo string) error
+}
@@ -3,15 +3,23 @@ package main
 import (
 	"context"
 	"fmt"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"licenseplate-plugin/internal/broker"
 	"licenseplate-plugin/internal/database"
 	"licenseplate-plugin/internal/handlers"
+	"licenseplate-plugin/internal/ingest"
+	"licenseplate-plugin/internal/outbox"
 	evt "licenseplate-plugin/internal/events"
 	"licenseplate-plugin/internal/services"
+	"licenseplate-plugin/internal/storage"
 	"licenseplate-plugin/internal/eventbus"
 
 	"github.com/gin-gonic/gin"
@@ -19,9 +27,6 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-// Global Redis client for event bus
-var redisClient *redis.Client
-
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
@@ -32,58 +37,124 @@ func main() {
 	host := getEnv("HOST", "localhost")
 	baseAPIRoute := getEnv("BASE_API_ROUTE", "/api/licenseplate")
 	databaseURL := getEnv("DATABASE_URL", "")
+	instanceID := getEnv("INSTANCE_ID", defaultInstanceID())
 
 	if databaseURL == "" {
 		log.Fatal("DATABASE_URL environment variable is required")
 	}
 
-	// Initialize Redis event bus
-	initRedis()
-
-	// Initialize database
-	db := database.NewDatabase(databaseURL)
+	// Initialize database connection pool
+	db, err := database.NewDatabase(databaseURL, database.Options{
+		MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 25),
+		MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 5),
+		ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		ConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+	})
+	if err != nil {
+		log.Fatal("Failed to open database:", err)
+	}
+	defer db.Close()
 
 	// Test database connection
-	if conn, err := db.GetConnection(); err != nil {
+	pingCtx, cancelPing := context.WithTimeout(context.Background(), 10*time.Second)
+	err = db.Ping(pingCtx)
+	cancelPing()
+	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
-	} else {
-		conn.Close()
-		log.Println("Successfully connected to database")
+	}
+	log.Println("Successfully connected to database")
+
+	// CLI subcommands (e.g. `licenseplate migrate status`) run and exit
+	if runCommand(context.Background(), db, os.Args[1:]) {
+		return
+	}
+
+	// Apply pending migrations; an advisory lock keeps replicas from racing
+	if getEnv("AUTO_MIGRATE", "true") == "true" {
+		if err := applyMigrations(context.Background(), db); err != nil {
+			log.Fatal("Failed to apply database migrations:", err)
+		}
 	}
 
 	// Initialize services
-	licensePlateService := services.NewLicensePlateService(db)
+	licensePlateService := services.NewLicensePlateService(storage.NewPostgresStore(db))
+	licensePlateService.SetEventSource(services.DefaultEventSource + "/" + instanceID)
+	licensePlateService.SetProcessedEventTTL(getEnvDuration("PROCESSED_EVENT_TTL", services.DefaultProcessedEventTTL))
+	licensePlateService.SetClockSkewThreshold(getEnvDuration("CLOCK_SKEW_THRESHOLD", services.DefaultClockSkewThreshold))
 
 	// Register with broker
 	go broker.RegisterWithBroker()
 
-	// Start event listener (subscribes to 'events' channel)
-	go func() {
-		ctx := context.Background()
-		_ = eventbus.Listen(ctx, redisClient, "events", func(channel, message string) {
-			// Dispatch incoming events to typed handlers
-			// use the licensePlateService to let handlers call service-layer logic
-			// non-blocking: dispatcher will start handlers asynchronously
-			imported := licensePlateService
-			// call into the events dispatcher
-			// note: package imported below to avoid unused import until file edited
-			eventDispatchWrapper(ctx, imported, message)
-		})
-	}()
+	// Initialize event bus
+	transport := getEnv("EVENTBUS_TRANSPORT", "pubsub")
+	bus := newEventBus(transport, instanceID)
+	defer bus.Close()
 
-	// Start background outbox publisher to reliably deliver events from DB to Redis
-	ctx := context.Background()
+	// Background work stops on SIGINT/SIGTERM; see the shutdown sequence below
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Route incoming events through the handler registry
+	eventMetrics := evt.NewMetrics()
+	registry := evt.NewRegistry()
+	registry.Use(evt.Logging(), eventMetrics.Middleware(), evt.Recovery())
+	// Ignore events published by any replica of this plugin
+	registry.SkipOwnEvents(services.DefaultEventSource)
+	handlers.RegisterEventHandlers(registry, licensePlateService)
+	// Keep messages that fail to dispatch for inspection and retry
+	registry.SetDeadLetterStore(licensePlateService)
+	log.Printf("Registered event handlers for: %v", registry.Types())
+
+	// Handle events on a bounded worker pool
+	overflow, err := evt.ParseOverflowPolicy(getEnv("EVENT_OVERFLOW", string(evt.OverflowBlock)))
+	if err != nil {
+		log.Fatal(err)
+	}
+	pool := evt.NewPool(registry, evt.PoolConfig{
+		Workers:   getEnvInt("EVENT_WORKERS", 4),
+		QueueSize: getEnvInt("EVENT_QUEUE_SIZE", 256),
+		Overflow:  overflow,
+	}, licensePlateService)
+
+	// Start event listener (subscribes to 'events' channel). Streams entries
+	// are acked after handling, so the listener waits for each one.
+	handle := pool.Submit
+	if transport == "streams" {
+		handle = pool.Process
+	}
+	if err := bus.Subscribe(ctx, services.EventsChannel, handle); err != nil {
+		log.Printf("Warning: failed to subscribe to %s: %v", services.EventsChannel, err)
+	}
+
+	// Start background outbox publisher to reliably deliver events from DB to the bus
 	// run every 10s, process up to 50 events per tick
-	startOutboxPublisher(ctx, licensePlateService, redisClient, 10*time.Second, 50)
+	startOutboxPublisher(ctx, db, licensePlateService, bus, instanceID, 10*time.Second, 50)
+
+	// Remove sent outbox events past the retention period, on the same context
+	outbox.NewRetentionJob(licensePlateService, outbox.RetentionConfig{
+		Retention: getEnvDuration("OUTBOX_RETENTION", outbox.DefaultRetention),
+		BatchSize: getEnvInt("OUTBOX_RETENTION_BATCH_SIZE", outbox.DefaultRetentionBatchSize),
+		Archive:   getEnv("OUTBOX_ARCHIVE", "false") == "true",
+	}).Start(ctx, getEnvDuration("OUTBOX_RETENTION_INTERVAL", time.Hour))
+
+	// Remove processed-events ledger entries past PROCESSED_EVENT_TTL
+	startProcessedEventsPurge(ctx, licensePlateService, getEnvDuration("PROCESSED_EVENT_PURGE_INTERVAL", time.Hour), 500)
+
+	// Publish access.expired for registrations whose access has run out
+	startAccessExpirySweep(ctx, licensePlateService, getEnvDuration("ACCESS_EXPIRY_SWEEP_INTERVAL", time.Minute), 100)
 
 	// Setup Gin router
 	router := gin.Default()
 
+	// Propagate X-Correlation-ID into emitted events
+	router.Use(handlers.CorrelationID())
+
 	// CORS middleware
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Correlation-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Correlation-ID")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -91,14 +162,45 @@ func main() {
 		c.Next()
 	})
 
-	// Health check endpoint
+	// Health check endpoint. A lost event bus reports "degraded" rather than
+	// failing the check: the listener resubscribes on its own and restarting
+	// the plugin would not bring Redis back.
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "healthy", "service": "licenseplate-plugin"})
+		status, busState := "healthy", "connected"
+		if !bus.Connected() {
+			status, busState = "degraded", "disconnected"
+		}
+		c.JSON(200, gin.H{"status": status, "service": "licenseplate-plugin", "eventbus": busState})
 	})
 
 	// Initialize handlers
-	handler := handlers.NewLicensePlateHandler(licensePlateService, redisClient)
-	webhookHandler := handlers.NewWebhookHandler(licensePlateService)
+	handler := handlers.NewLicensePlateHandler(licensePlateService)
+	adapters := ingest.NewRegistry()
+	if err := adapters.Register(ingest.XPOTSAdapter{}); err != nil {
+		log.Fatalf("Failed to register XPOTS adapter: %v", err)
+	}
+	if path := getEnv("WEBHOOK_MAPPINGS_FILE", ""); path != "" {
+		mappings, err := ingest.LoadFieldMappings(path)
+		if err != nil {
+			log.Fatalf("Failed to load webhook mappings: %v", err)
+		}
+		for _, m := range mappings {
+			adapter, err := ingest.NewFieldMappingAdapter(m)
+			if err == nil {
+				err = adapters.Register(adapter)
+			}
+			if err != nil {
+				log.Fatalf("Failed to register webhook adapter: %v", err)
+			}
+		}
+	}
+	log.Printf("Webhook adapters: %v", adapters.Vendors())
+	webhookHandler := handlers.NewWebhookHandler(licensePlateService, adapters)
+	cameraSites, err := ingest.ParseCameraSites(getEnv("CAMERA_SITES", ""))
+	if err != nil {
+		log.Fatalf("Invalid CAMERA_SITES: %v", err)
+	}
+	webhookHandler.SetCameraSites(cameraSites)
 
 	// Register routes
 	api := router.Group(baseAPIRoute)
@@ -107,19 +209,90 @@ func main() {
 		api.GET("/records", handler.GetAllRecords)
 		api.GET("/records/:plate", handler.GetRecord)
 		api.GET("/records/:plate/events", handler.GetParkingEvents)
+		api.GET("/records/:plate/stays", handler.GetStays)
+		api.GET("/occupancy", handler.GetOccupancy)
 		api.DELETE("/records/:plate", handler.DeleteRecord)
+
+		// Plates that raise watchlist.hit when detected
+		api.GET("/watchlist", handler.GetWatchlist)
+		api.POST("/watchlist", handler.AddToWatchlist)
+		api.DELETE("/watchlist/:plate", handler.RemoveFromWatchlist)
 		
-		// Webhook endpoints
-		api.POST("/webhook/xpots", webhookHandler.HandleXPOTSWebhook)
+		// Camera webhooks, one route per vendor adapter. Requests must be
+		// signed with a key issued through the admin API, or with
+		// WEBHOOK_SECRET if they name no key. Issued keys replace the
+		// secret, so the routes are mounted without it and reject unsigned
+		// requests
+		fallbackSecret := getEnv("WEBHOOK_SECRET", "")
+		if fallbackSecret == "" {
+			log.Println("WEBHOOK_SECRET not set - camera webhooks must be signed with an issued key")
+		}
+		verifier := handlers.NewSignatureVerifier(licensePlateService, fallbackSecret, getEnvDuration("WEBHOOK_TOLERANCE", 5*time.Minute))
+		// Used signatures go to the shared ledger so a replay to another
+		// replica is rejected too
+		verifier.SetLedger(licensePlateService)
+		api.POST("/webhook/:vendor", handlers.RequireSignature(verifier), webhookHandler.HandleWebhook)
+		api.POST("/webhook/:vendor/batch", handlers.RequireSignature(verifier), webhookHandler.HandleBatchWebhook)
 		api.GET("/webhook/info", webhookHandler.GetWebhookInfo)
 	}
 
+	// Admin endpoints are only exposed when an admin key is configured
+	if adminAPIKey := getEnv("ADMIN_API_KEY", ""); adminAPIKey != "" {
+		outboxHandler := handlers.NewOutboxHandler(licensePlateService)
+
+		admin := api.Group("/admin", handlers.RequireAPIKey(adminAPIKey))
+		{
+			admin.GET("/outbox", outboxHandler.ListEvents)
+			admin.GET("/outbox/stats", outboxHandler.GetStats)
+			admin.GET("/outbox/:id", outboxHandler.GetEvent)
+			admin.POST("/outbox/:id/retry", outboxHandler.RetryEvent)
+			admin.POST("/outbox/:id/cancel", outboxHandler.CancelEvent)
+
+			admin.GET("/events/stats", handlers.NewEventsHandler(eventMetrics, pool).GetStats)
+			admin.POST("/replay", handlers.NewReplayHandler(licensePlateService).Replay)
+
+			deadLetterHandler := handlers.NewDeadLetterHandler(licensePlateService, registry)
+			admin.GET("/dead-letters", deadLetterHandler.ListDeadLetters)
+			admin.GET("/dead-letters/:id", deadLetterHandler.GetDeadLetter)
+			admin.POST("/dead-letters/:id/retry", deadLetterHandler.RetryDeadLetter)
+			admin.DELETE("/dead-letters/:id", deadLetterHandler.DiscardDeadLetter)
+
+			credentialHandler := handlers.NewCredentialHandler(licensePlateService)
+			admin.GET("/webhook-keys", credentialHandler.ListCredentials)
+			admin.POST("/webhook-keys", credentialHandler.IssueCredential)
+			admin.POST("/webhook-keys/:id/rotate", credentialHandler.RotateCredential)
+			admin.POST("/webhook-keys/:id/revoke", credentialHandler.RevokeCredential)
+		}
+	} else {
+		log.Println("WARNING: ADMIN_API_KEY not set - admin endpoints are disabled")
+	}
+
 	// Start server
-	addr := fmt.Sprintf("%s:%s", host, port)
-	log.Printf("License Plate Recognition Plugin running on http://%s%s", addr, baseAPIRoute)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	server := &http.Server{Addr: ":" + port, Handler: router}
+	go func() {
+		addr := fmt.Sprintf("%s:%s", host, port)
+		log.Printf("License Plate Recognition Plugin running on http://%s%s", addr, baseAPIRoute)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	// On a signal, ctx is cancelled, which ends the bus subscription and the
+	// background jobs. Then in-flight HTTP requests and queued event
+	// handlers get SHUTDOWN_TIMEOUT to finish.
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), getEnvDuration("SHUTDOWN_TIMEOUT", 15*time.Second))
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP server shutdown: %v", err)
 	}
+	if err := pool.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Event handlers did not finish in time: %v", err)
+	}
+	log.Println("Shutdown complete")
 }
 
 func getEnv(key, fallback string) string {
@@ -129,11 +302,59 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
-// initRedis initializes the global Redis client for the event bus
-func initRedis() {
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid value for %s (%q), using default %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid value for %s (%q), using default %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// newEventBus builds the event bus for transport: "pubsub" (Redis
+// PUB/SUB, fire-and-forget), "streams" (Redis Streams consumer group with
+// acknowledgements) or "memory" (in-process, for running without Redis).
+func newEventBus(transport, instanceID string) eventbus.EventBus {
+	switch transport {
+	case "memory":
+		log.Println("Using in-memory event bus; events stay within this process")
+		return eventbus.NewMemoryBus()
+	case "pubsub":
+		return eventbus.NewRedisPubSub(newRedisClient())
+	case "streams":
+		opts := eventbus.DefaultStreamOptions(instanceID)
+		opts.Group = getEnv("EVENTBUS_GROUP", opts.Group)
+		opts.MaxLen = int64(getEnvInt("EVENTBUS_STREAM_MAXLEN", int(opts.MaxLen)))
+		opts.ClaimMinIdle = getEnvDuration("EVENTBUS_CLAIM_MIN_IDLE", opts.ClaimMinIdle)
+		return eventbus.NewRedisStreams(newRedisClient(), opts)
+	default:
+		log.Fatalf("Unknown EVENTBUS_TRANSPORT %q (expected pubsub, streams or memory)", transport)
+		return nil
+	}
+}
+
+// newRedisClient connects to the Redis event bus
+func newRedisClient() *redis.Client {
 	redisAddr := getEnv("HUB_BUS_ADDR", "localhost:6379")
 
-	redisClient = redis.NewClient(&redis.Options{
+	client := redis.NewClient(&redis.Options{
 		Addr:     redisAddr,
 		Password: "",
 		DB:       0,
@@ -141,31 +362,44 @@ func initRedis() {
 
 	// Test connection
 	ctx := context.Background()
-	_, err := redisClient.Ping(ctx).Result()
+	_, err := client.Ping(ctx).Result()
 	if err != nil {
 		log.Printf("Warning: Failed to connect to Redis at %s: %v", redisAddr, err)
-		log.Println("Plugin will continue without event bus functionality")
+		log.Println("Event bus subscriptions will keep retrying until Redis is reachable")
 	} else {
 		log.Printf("✓ Connected to Redis event bus at %s", redisAddr)
 	}
+	return client
 }
 
-// eventDispatchWrapper is a tiny indirection so we can call the dispatcher
-// without adding complex logic inline in the listener callback.
-func eventDispatchWrapper(ctx context.Context, svc *services.LicensePlateService, message string) {
-	evt.Dispatch(ctx, svc, message)
-}
+// startOutboxPublisher runs a background goroutine that claims pending
+// outbox events from the database and publishes them on bus. It
+// wakes on the outbox_events NOTIFY and polls every interval as a
+// fallback. Claimed rows are leased to this instance so several replicas
+// can share the outbox without double publishing.
+func startOutboxPublisher(ctx context.Context, db *database.Database, svc *services.LicensePlateService, bus eventbus.Publisher, instanceID string, interval time.Duration, batchSize int) {
+	retry := outbox.DefaultRetryPolicy()
+	retry.BaseDelay = getEnvDuration("OUTBOX_RETRY_BASE_DELAY", retry.BaseDelay)
+	retry.MaxDelay = getEnvDuration("OUTBOX_RETRY_MAX_DELAY", retry.MaxDelay)
+	retry.MaxAttempts = getEnvInt("OUTBOX_MAX_ATTEMPTS", retry.MaxAttempts)
 
-// startOutboxPublisher runs a background goroutine that periodically
-// fetches pending outbox events from the database and publishes them
-// to the Redis event bus. On success the event is marked as sent; on
-// failure the attempts counter is incremented and the error recorded.
-func startOutboxPublisher(ctx context.Context, svc *services.LicensePlateService, client *redis.Client, interval time.Duration, batchSize int) {
-	if client == nil {
-		log.Println("[OutboxPublisher] redis client is nil; outbox publisher disabled")
-		return
+	wake, err := db.Listen(ctx, "outbox_events")
+	if err != nil {
+		log.Printf("[OutboxPublisher] LISTEN outbox_events failed, falling back to polling: %v", err)
 	}
 
+	outbox.NewPublisher(svc, bus, outbox.Config{
+		Owner:     instanceID,
+		BatchSize: batchSize,
+		Lease:     getEnvDuration("OUTBOX_LEASE", time.Minute),
+		Retry:     retry,
+	}).Start(ctx, interval, wake)
+}
+
+// startAccessExpirySweep runs a background goroutine that publishes
+// access.expired for plates whose access has expired, every interval until
+// ctx is cancelled.
+func startAccessExpirySweep(ctx context.Context, svc *services.LicensePlateService, interval time.Duration, batchSize int) {
 	go func() {
 		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
@@ -173,33 +407,65 @@ func startOutboxPublisher(ctx context.Context, svc *services.LicensePlateService
 		for {
 			select {
 			case <-ctx.Done():
-				log.Println("[OutboxPublisher] context canceled, stopping publisher")
 				return
 			case <-ticker.C:
-				events, err := svc.FetchPendingOutboxEvents(batchSize)
+			}
+
+			for ctx.Err() == nil {
+				n, err := svc.NotifyExpiredAccess(ctx, batchSize)
 				if err != nil {
-					log.Printf("[OutboxPublisher] fetch error: %v", err)
-					continue
+					log.Printf("[AccessExpiry] sweep failed: %v", err)
+					break
 				}
+				if n > 0 {
+					log.Printf("[AccessExpiry] published access.expired for %d plates", n)
+				}
+				if n < batchSize {
+					break
+				}
+			}
+		}
+	}()
+}
+
+// startProcessedEventsPurge runs a background goroutine that removes expired
+// processed-events ledger entries, every interval until ctx is cancelled.
+func startProcessedEventsPurge(ctx context.Context, svc *services.LicensePlateService, interval time.Duration, batchSize int) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
 
-				for _, e := range events {
-					// attempt publish
-					if err := eventbus.Publish(ctx, client, e.Channel, e.Payload); err != nil {
-						log.Printf("[OutboxPublisher] publish failed id=%d: %v", e.ID, err)
-						_ = svc.IncrementOutboxAttempts(e.ID, err.Error())
-						continue
-					}
-
-					// mark as sent
-					if err := svc.MarkOutboxSent(e.ID); err != nil {
-						log.Printf("[OutboxPublisher] mark sent failed id=%d: %v", e.ID, err)
-						_ = svc.IncrementOutboxAttempts(e.ID, "mark sent failed: "+err.Error())
-						continue
-					}
-
-					log.Printf("[OutboxPublisher] published and marked sent id=%d channel=%s", e.ID, e.Channel)
+			var total int64
+			for ctx.Err() == nil {
+				n, err := svc.PurgeProcessedEvents(ctx, batchSize)
+				total += n
+				if err != nil {
+					log.Printf("[ProcessedEvents] purge failed: %v", err)
+					break
 				}
+				if n < int64(batchSize) {
+					break
+				}
+			}
+			if total > 0 {
+				log.Printf("[ProcessedEvents] removed %d expired processed event(s)", total)
 			}
 		}
 	}()
 }
+
+// defaultInstanceID identifies this process when INSTANCE_ID is not set.
+func defaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "licenseplate"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
@@ -0,0 +1,2 @@
+-- Revert 001: drop the license_plates table and its indexes
+DROP TABLE IF EXISTS license_plates;
@@ -0,0 +1,9 @@
+-- Revert 002: remove visitor support columns
+
+DROP INDEX IF EXISTS idx_visitor_type;
+DROP INDEX IF EXISTS idx_access_expires_at;
+
+ALTER TABLE license_plates
+DROP COLUMN IF EXISTS purpose,
+DROP COLUMN IF EXISTS access_expires_at,
+DROP COLUMN IF EXISTS visitor_type;
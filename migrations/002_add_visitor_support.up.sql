@@ -2,15 +2,15 @@
 -- This adds visitor types, access expiration, and purpose tracking
 
 ALTER TABLE license_plates 
-ADD COLUMN visitor_type VARCHAR(50) DEFAULT 'guest' CHECK (visitor_type IN ('guest', 'visitor', 'staff', 'delivery', 'contractor', 'vip')),
-ADD COLUMN access_expires_at TIMESTAMP,
-ADD COLUMN purpose TEXT;
+ADD COLUMN IF NOT EXISTS visitor_type VARCHAR(50) DEFAULT 'guest' CHECK (visitor_type IN ('guest', 'visitor', 'staff', 'delivery', 'contractor', 'vip')),
+ADD COLUMN IF NOT EXISTS access_expires_at TIMESTAMP,
+ADD COLUMN IF NOT EXISTS purpose TEXT;
 
 -- Create index for quick lookup of expired access
-CREATE INDEX idx_access_expires_at ON license_plates(access_expires_at) WHERE access_expires_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_access_expires_at ON license_plates(access_expires_at) WHERE access_expires_at IS NOT NULL;
 
 -- Create index for visitor type filtering
-CREATE INDEX idx_visitor_type ON license_plates(visitor_type);
+CREATE INDEX IF NOT EXISTS idx_visitor_type ON license_plates(visitor_type);
 
 COMMENT ON COLUMN license_plates.visitor_type IS 'Type of visitor: guest (hotel guest), visitor (temporary), staff, delivery, contractor, vip';
 COMMENT ON COLUMN license_plates.access_expires_at IS 'When temporary access expires (NULL = no expiration)';
@@ -0,0 +1,10 @@
+-- Revert 003: drop parking events and the reservation link columns
+
+DROP INDEX IF EXISTS idx_license_plates_reservation;
+DROP INDEX IF EXISTS idx_license_plates_guest;
+
+ALTER TABLE license_plates
+DROP COLUMN IF EXISTS reservation_id,
+DROP COLUMN IF EXISTS guest_id;
+
+DROP TABLE IF EXISTS parking_events;
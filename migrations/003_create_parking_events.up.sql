@@ -14,10 +14,10 @@ CREATE TABLE IF NOT EXISTS parking_events (
 );
 
 -- Create indexes for fast queries
-CREATE INDEX idx_parking_events_plate ON parking_events(plate_number);
-CREATE INDEX idx_parking_events_time ON parking_events(event_time DESC);
-CREATE INDEX idx_parking_events_type ON parking_events(event_type);
-CREATE INDEX idx_parking_events_plate_time ON parking_events(plate_number, event_time DESC);
+CREATE INDEX IF NOT EXISTS idx_parking_events_plate ON parking_events(plate_number);
+CREATE INDEX IF NOT EXISTS idx_parking_events_time ON parking_events(event_time DESC);
+CREATE INDEX IF NOT EXISTS idx_parking_events_type ON parking_events(event_type);
+CREATE INDEX IF NOT EXISTS idx_parking_events_plate_time ON parking_events(plate_number, event_time DESC);
 
 -- Add comments for documentation
 COMMENT ON TABLE parking_events IS 'Audit trail of all vehicle entry/exit events';
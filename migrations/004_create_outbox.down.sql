@@ -0,0 +1,2 @@
+-- Revert 004: drop the outbox table
+DROP TABLE IF EXISTS outbox_events;
@@ -10,6 +10,6 @@ CREATE TABLE IF NOT EXISTS outbox_events (
     sent_at TIMESTAMP
 );
 
-CREATE INDEX idx_outbox_events_sent_at ON outbox_events(sent_at NULLS FIRST, created_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_sent_at ON outbox_events(sent_at NULLS FIRST, created_at);
 
 COMMENT ON TABLE outbox_events IS 'Outbox table for reliable event publishing to Redis';
@@ -0,0 +1,7 @@
+-- Revert 005: drop outbox lease columns
+
+DROP INDEX IF EXISTS idx_outbox_events_pending;
+
+ALTER TABLE outbox_events
+DROP COLUMN IF EXISTS locked_until,
+DROP COLUMN IF EXISTS locked_by;
@@ -0,0 +1,12 @@
+-- Migration 005: Lease columns so multiple publishers can share the outbox
+-- A publisher claims rows by setting locked_by/locked_until under
+-- FOR UPDATE SKIP LOCKED; other replicas skip rows with an unexpired lease.
+
+ALTER TABLE outbox_events
+ADD COLUMN IF NOT EXISTS locked_by VARCHAR(100),
+ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(created_at) WHERE sent_at IS NULL;
+
+COMMENT ON COLUMN outbox_events.locked_by IS 'Instance id of the publisher currently holding the lease';
+COMMENT ON COLUMN outbox_events.locked_until IS 'Lease expiry; after this another publisher may claim the row';
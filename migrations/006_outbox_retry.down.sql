@@ -0,0 +1,8 @@
+-- Revert 006: drop the outbox retry schedule and status
+
+DROP INDEX IF EXISTS idx_outbox_events_status;
+DROP INDEX IF EXISTS idx_outbox_events_due;
+
+ALTER TABLE outbox_events
+DROP COLUMN IF EXISTS next_attempt_at,
+DROP COLUMN IF EXISTS status;
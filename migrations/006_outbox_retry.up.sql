@@ -0,0 +1,16 @@
+-- Migration 006: Retry schedule and dead-lettering for the outbox
+-- Failed rows are retried at next_attempt_at with exponential backoff.
+-- Rows that exceed the max attempt count move to status 'failed'
+-- (dead-lettered) until an operator requeues or discards them.
+
+ALTER TABLE outbox_events
+ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed', 'discarded')),
+ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+UPDATE outbox_events SET status = 'sent' WHERE sent_at IS NOT NULL AND status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_due ON outbox_events(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outbox_events_status ON outbox_events(status, created_at);
+
+COMMENT ON COLUMN outbox_events.status IS 'pending, sent, failed (dead-lettered) or discarded';
+COMMENT ON COLUMN outbox_events.next_attempt_at IS 'Earliest time the publisher may try this row again';
@@ -0,0 +1,3 @@
+-- Revert 007: drop the outbox archive
+DROP INDEX IF EXISTS idx_outbox_events_sent;
+DROP TABLE IF EXISTS outbox_events_archive;
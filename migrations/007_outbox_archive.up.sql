@@ -0,0 +1,18 @@
+-- Migration 007: Archive table for sent outbox events
+-- The retention job moves (or deletes) sent rows older than the retention
+-- period so outbox_events only holds recent traffic.
+
+CREATE TABLE IF NOT EXISTS outbox_events_archive (
+    id INT PRIMARY KEY,
+    channel VARCHAR(100) NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    sent_at TIMESTAMP,
+    archived_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_archive_sent_at ON outbox_events_archive(sent_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_sent ON outbox_events(sent_at) WHERE status = 'sent';
+
+COMMENT ON TABLE outbox_events_archive IS 'Sent outbox events moved out of outbox_events by the retention job';
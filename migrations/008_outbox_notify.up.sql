@@ -0,0 +1,17 @@
+-- Migration 008: Wake the outbox publisher when events are inserted
+-- A statement-level trigger sends one NOTIFY per inserting statement; the
+-- publisher listens on the outbox_events channel and drains the outbox
+-- immediately instead of waiting for its next poll.
+
+CREATE OR REPLACE FUNCTION notify_outbox_events() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('outbox_events', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS outbox_events_notify ON outbox_events;
+CREATE TRIGGER outbox_events_notify
+    AFTER INSERT ON outbox_events
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION notify_outbox_events();
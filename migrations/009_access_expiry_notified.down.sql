@@ -0,0 +1,3 @@
+-- Revert 009: drop access expiry notification tracking
+DROP INDEX IF EXISTS idx_license_plates_access_expiry_due;
+ALTER TABLE license_plates DROP COLUMN IF EXISTS access_expiry_notified_at;
@@ -0,0 +1,12 @@
+-- Migration 009: Track which access expirations have been announced
+-- The expiry sweep sets access_expiry_notified_at when it publishes
+-- access.expired, so each expiration is announced once. Changing
+-- access_expires_at clears it again.
+
+ALTER TABLE license_plates
+ADD COLUMN IF NOT EXISTS access_expiry_notified_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_license_plates_access_expiry_due ON license_plates(access_expires_at)
+    WHERE access_expires_at IS NOT NULL AND access_expiry_notified_at IS NULL;
+
+COMMENT ON COLUMN license_plates.access_expiry_notified_at IS 'When access.expired was published for the current access_expires_at';
@@ -0,0 +1,2 @@
+-- Revert 010: drop the watchlist
+DROP TABLE IF EXISTS watchlist;
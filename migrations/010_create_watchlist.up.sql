@@ -0,0 +1,9 @@
+-- Migration 010: Watchlist of plates that raise watchlist.hit when detected
+
+CREATE TABLE IF NOT EXISTS watchlist (
+    plate_number VARCHAR(20) PRIMARY KEY,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE watchlist IS 'Plates that publish watchlist.hit whenever a camera detects them';
@@ -0,0 +1,2 @@
+-- Revert 011: drop the inbound event spill table
+DROP TABLE IF EXISTS inbound_event_spill;
@@ -0,0 +1,12 @@
+-- Migration 011: Overflow table for the inbound event worker pool
+-- With EVENT_OVERFLOW=spill, bus messages that arrive while the dispatcher
+-- queue is full are parked here and fed back once the queue drains.
+
+CREATE TABLE IF NOT EXISTS inbound_event_spill (
+    id BIGSERIAL PRIMARY KEY,
+    channel VARCHAR(100) NOT NULL,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE inbound_event_spill IS 'Inbound bus messages spilled by the dispatcher while its queue was full';
@@ -0,0 +1,2 @@
+-- Revert 012: drop the processed events ledger
+DROP TABLE IF EXISTS processed_events;
@@ -0,0 +1,16 @@
+-- Migration 012: Ledger of processed events for idempotent handling
+-- Bus handlers and webhooks record each event id here in the same
+-- transaction as their changes, so a redelivered event is recognised and
+-- skipped. Rows expire after a TTL and are purged by the retention job.
+
+CREATE TABLE IF NOT EXISTS processed_events (
+    consumer VARCHAR(100) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    processed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (consumer, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_processed_events_expires_at ON processed_events(expires_at);
+
+COMMENT ON TABLE processed_events IS 'Event ids already handled per consumer, kept until expires_at to drop redeliveries';
@@ -0,0 +1,2 @@
+-- Revert 013: drop the inbound dead letter table
+DROP TABLE IF EXISTS inbound_dead_letters;
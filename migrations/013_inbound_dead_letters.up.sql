@@ -0,0 +1,24 @@
+-- Migration 013: Dead letters for inbound bus messages
+-- Messages the dispatcher cannot handle (invalid JSON, no handler for the
+-- type, handler errors) are kept here for inspection and retry instead of
+-- being dropped. Repeated failures of the same event in the same handler
+-- update one row and count attempts.
+
+CREATE TABLE IF NOT EXISTS inbound_dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    channel VARCHAR(100) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100) NOT NULL DEFAULT '',
+    handler VARCHAR(100) NOT NULL DEFAULT '',
+    payload TEXT NOT NULL,
+    error TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    first_failed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_failed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (event_id, handler)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbound_dead_letters_last_failed_at ON inbound_dead_letters(last_failed_at);
+
+COMMENT ON TABLE inbound_dead_letters IS 'Inbound bus messages that failed to dispatch, one row per event and handler';
+COMMENT ON COLUMN inbound_dead_letters.handler IS 'Handler that failed; empty when the message never reached a handler';
@@ -0,0 +1,3 @@
+-- Revert 014: drop webhook credentials and the key recorded on parking events
+ALTER TABLE parking_events DROP COLUMN IF EXISTS webhook_key_id;
+DROP TABLE IF EXISTS webhook_credentials;
@@ -0,0 +1,28 @@
+-- Migration 014: Per-camera and per-site webhook credentials
+-- Each camera or site signs its webhooks with its own key, selected by the
+-- X-Webhook-Key-Id header. Rotating a key issues a new one and keeps the
+-- old one valid until expires_at, so both work during the changeover.
+
+CREATE TABLE IF NOT EXISTS webhook_credentials (
+    id VARCHAR(64) PRIMARY KEY,
+    site VARCHAR(100) NOT NULL DEFAULT '',
+    camera_id VARCHAR(100) NOT NULL DEFAULT '',
+    secret TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    rotated_to VARCHAR(64),
+    CHECK (site <> '' OR camera_id <> '')
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_credentials_camera ON webhook_credentials(camera_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_credentials_site ON webhook_credentials(site);
+
+COMMENT ON TABLE webhook_credentials IS 'HMAC secrets for camera webhooks, scoped to a site or camera';
+COMMENT ON COLUMN webhook_credentials.camera_id IS 'When set, the key only authenticates detections from this camera';
+COMMENT ON COLUMN webhook_credentials.expires_at IS 'End of the rotation overlap; NULL while the key is current';
+
+ALTER TABLE parking_events ADD COLUMN IF NOT EXISTS webhook_key_id VARCHAR(64);
+
+COMMENT ON COLUMN parking_events.webhook_key_id IS 'Webhook credential that authenticated the detection';
@@ -0,0 +1,5 @@
+-- Revert 015: drop receive time and ordering flags from parking events
+ALTER TABLE parking_events
+DROP COLUMN IF EXISTS out_of_order,
+DROP COLUMN IF EXISTS clock_skew,
+DROP COLUMN IF EXISTS received_at;
@@ -0,0 +1,20 @@
+-- Migration 015: Camera time versus receive time for parking events
+-- event_time now holds the time the camera reported. received_at records
+-- when the plugin received the detection, so delayed or buffered webhooks
+-- keep their real time, and differences beyond the skew threshold are
+-- flagged.
+
+ALTER TABLE parking_events ADD COLUMN IF NOT EXISTS received_at TIMESTAMP;
+UPDATE parking_events SET received_at = COALESCE(created_at, event_time) WHERE received_at IS NULL;
+ALTER TABLE parking_events
+ALTER COLUMN received_at SET DEFAULT NOW(),
+ALTER COLUMN received_at SET NOT NULL;
+
+ALTER TABLE parking_events
+ADD COLUMN IF NOT EXISTS clock_skew BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS out_of_order BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN parking_events.event_time IS 'When the camera detected the vehicle, as reported by the camera';
+COMMENT ON COLUMN parking_events.received_at IS 'When the plugin received the detection';
+COMMENT ON COLUMN parking_events.clock_skew IS 'event_time and received_at differed by more than the skew threshold';
+COMMENT ON COLUMN parking_events.out_of_order IS 'A later detection of the plate had already been received';
@@ -0,0 +1,12 @@
+// Package migrations embeds the versioned SQL schema so the binary can apply
+// it without the files being present on disk.
+//
+// Files are named NNN_description.up.sql with an optional matching
+// NNN_description.down.sql that reverts it. Up migrations must be idempotent
+// so they can be replayed against databases that were migrated by hand.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
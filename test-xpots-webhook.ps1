@@ -5,12 +5,37 @@ Write-Host "`n=== XPOTS Webhook Integration Test ===" -ForegroundColor Cyan
 
 # Configuration
 $baseUrl = "http://localhost:9002/api/licenseplate"
-$webhookKey = "test-webhook-key-12345"
+$keyId = "your-key-id"
+$keySecret = "your-key-secret"
 
-Write-Host "`nNote: Make sure to set WEBHOOK_API_KEY=$webhookKey in your .env file" -ForegroundColor Yellow
+Write-Host "`nNote: Issue a webhook key for CAM-001 and CAM-002 (POST $baseUrl/admin/webhook-keys) and set `$keyId and `$keySecret above" -ForegroundColor Yellow
 Write-Host "Press any key to continue..." -ForegroundColor Yellow
 $null = $Host.UI.RawUI.ReadKey("NoEcho,IncludeKeyDown")
 
+# Sends a webhook signed with the key: X-Webhook-Signature is the hex
+# HMAC-SHA256 of "<timestamp>.<raw body>", keyed with the secret
+function Send-SignedWebhook([string]$body, [string]$secret = $keySecret) {
+    $timestamp = [DateTimeOffset]::UtcNow.ToUnixTimeSeconds().ToString()
+    $hmac = New-Object System.Security.Cryptography.HMACSHA256
+    $hmac.Key = [Text.Encoding]::UTF8.GetBytes($secret)
+    $hash = $hmac.ComputeHash([Text.Encoding]::UTF8.GetBytes("$timestamp.$body"))
+    $signature = "sha256=" + (($hash | ForEach-Object { $_.ToString("x2") }) -join "")
+
+    try {
+        $response = Invoke-WebRequest -Method Post -Uri "$baseUrl/webhook/xpots" -UseBasicParsing `
+            -ContentType "application/json" `
+            -Headers @{
+                "X-Webhook-Key-Id"    = $keyId
+                "X-Webhook-Timestamp" = $timestamp
+                "X-Webhook-Signature" = $signature
+            } `
+            -Body ([Text.Encoding]::UTF8.GetBytes($body))
+        Write-Host "$($response.StatusCode) $($response.Content)"
+    } catch {
+        Write-Host "$([int]$_.Exception.Response.StatusCode) $($_.ErrorDetails.Message)" -ForegroundColor Red
+    }
+}
+
 # 1. Get webhook info
 Write-Host "`n1. Getting webhook configuration info..." -ForegroundColor Yellow
 curl.exe -X GET "$baseUrl/webhook/info"
@@ -28,10 +53,7 @@ $entryPayload = @{
     vehicle_type = "car"
 } | ConvertTo-Json
 
-curl.exe -X POST "$baseUrl/webhook/xpots" `
-    -H "Authorization: Bearer $webhookKey" `
-    -H "Content-Type: application/json" `
-    -d $entryPayload
+Send-SignedWebhook $entryPayload
 
 # 3. Verify the record was created
 Write-Host "`n`n3. Verifying record was created..." -ForegroundColor Yellow
@@ -50,10 +72,7 @@ $entry2Payload = @{
     direction = "in"
 } | ConvertTo-Json
 
-curl.exe -X POST "$baseUrl/webhook/xpots" `
-    -H "Authorization: Bearer $webhookKey" `
-    -H "Content-Type: application/json" `
-    -d $entry2Payload
+Send-SignedWebhook $entry2Payload
 
 # 5. Test webhook - exit event
 Write-Host "`n`n5. Sending exit event (vehicle leaving)..." -ForegroundColor Yellow
@@ -68,10 +87,7 @@ $exitPayload = @{
     direction = "out"
 } | ConvertTo-Json
 
-curl.exe -X POST "$baseUrl/webhook/xpots" `
-    -H "Authorization: Bearer $webhookKey" `
-    -H "Content-Type: application/json" `
-    -d $exitPayload
+Send-SignedWebhook $exitPayload
 
 # 6. Verify check-out was recorded
 Write-Host "`n`n6. Verifying check-out was recorded..." -ForegroundColor Yellow
@@ -82,16 +98,13 @@ curl.exe -X GET "$baseUrl/records/XPOTS-001"
 Write-Host "`n`n7. Getting all records..." -ForegroundColor Yellow
 curl.exe -X GET "$baseUrl/records"
 
-# 8. Test invalid API key
-Write-Host "`n`n8. Testing webhook with invalid API key (should fail)..." -ForegroundColor Yellow
-curl.exe -X POST "$baseUrl/webhook/xpots" `
-    -H "Authorization: Bearer wrong-key" `
-    -H "Content-Type: application/json" `
-    -d $entryPayload
+# 8. Test invalid signature
+Write-Host "`n`n8. Testing webhook signed with the wrong secret (should fail with 401)..." -ForegroundColor Yellow
+Send-SignedWebhook $entryPayload "wrong-secret"
 
 Write-Host "`n`n=== Test Complete ===" -ForegroundColor Green
 Write-Host "Review the responses above to verify:" -ForegroundColor Cyan
 Write-Host "  ✓ Entry events create new records" -ForegroundColor White
 Write-Host "  ✓ Exit events update check-out times" -ForegroundColor White
-Write-Host "  ✓ Invalid API keys are rejected" -ForegroundColor White
+Write-Host "  ✓ Invalid signatures are rejected" -ForegroundColor White
 Write-Host "  ✓ All data is stored in the database" -ForegroundColor White